@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// cocomoModel contains the coefficients of the basic COCOMO model for a type of project.
+type cocomoModel struct {
+	// effortFactor and effortExponent convert thousands of loc into person-months of effort.
+	effortFactor, effortExponent float64
+	// scheduleFactor and scheduleExponent convert person-months of effort into months of development.
+	scheduleFactor, scheduleExponent float64
+}
+
+// cocomoModels maps the -ct inputs to the models for each type of project.
+var cocomoModels = map[string]cocomoModel{
+	"organic":       {2.4, 1.05, 2.5, 0.38},
+	"semi-detached": {3.0, 1.12, 2.5, 0.35},
+	"embedded":      {3.6, 1.20, 2.5, 0.32},
+}
+
+// cocomoEstimate contains the estimated effort, schedule, and cost of developing an amount of code.
+type cocomoEstimate struct {
+	personMonths float64
+	months       float64
+	people       float64
+	cost         float64
+}
+
+// estimateCocomo estimates the effort, schedule, and cost of developing loc lines of code using the model chosen by -ct.
+func estimateCocomo(loc int) cocomoEstimate {
+	model := cocomoModels[*cocomoType]
+	var estimate cocomoEstimate
+	estimate.personMonths = model.effortFactor * math.Pow(float64(loc)/1_000, model.effortExponent)
+	estimate.months = model.scheduleFactor * math.Pow(estimate.personMonths, model.scheduleExponent)
+	if estimate.months > 0 {
+		estimate.people = estimate.personMonths / estimate.months
+	}
+	estimate.cost = estimate.personMonths * float64(*cocomoSalary) / 12 * *cocomoOverhead
+	return estimate
+}
+
+// format converts e into a row of printCocomo's output.
+func (e cocomoEstimate) format() string {
+	return fmt.Sprintf(
+		"$%s | %.1f | %.1f | %.1f",
+		addCommas(int(math.Round(e.cost))), e.personMonths, e.months, e.people,
+	)
+}
+
+/*
+printCocomo prints the COCOMO estimates of the effort, schedule, and cost of developing the loc in
+mainDir, followed by the estimates for each of its directories if multiple directories were searched.
+*/
+func printCocomo(mainDir *directory) {
+	fmt.Printf(
+		"\033[1mCOCOMO (%s, $%s salary, %.1fx overhead): cost | person-months | months | people\033[0m\n",
+		*cocomoType, addCommas(*cocomoSalary), *cocomoOverhead,
+	)
+	fmt.Printf("Total: %s\n", estimateCocomo(sumMapValues(mainDir.locCounts)).format())
+
+	// mainDir is fake if multiple directories were searched (see main function)
+	if mainDir.fullPath != "" {
+		return
+	}
+	for _, subdir := range mainDir.subdirectories {
+		// label the row with the searched directory, since subdir may have been compressed into a descendant
+		path := subdir.fullPath
+		for _, root := range searchRoots {
+			if path == root || strings.HasPrefix(path, root+pathSeparator) {
+				path = root
+				break
+			}
+		}
+		fmt.Printf("    %s: %s\n", relToCwd(path), estimateCocomo(sumMapValues(subdir.locCounts)).format())
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// column is a statistic which is printed in loc's output and can be used to sort results.
+type column struct {
+	// name is the column's label and its -s input.
+	name string
+	// dirCounts returns the column's counts by language for a directory.
+	dirCounts func(d *directory) map[string]int
+	// fileCount returns the column's count for a file, or is nil if it isn't printed by file.
+	fileCount func(f *file) int
+	// dirTotal returns the column's total across all languages for a directory, or is nil if it is the sum of dirCounts.
+	dirTotal func(d *directory) int
+	// format converts a count into a string.
+	format func(int) string
+	// noPercent is true if the column's counts aren't parts of a total, so -p doesn't apply to them.
+	noPercent bool
+	// shown returns whether the column should be printed, or is nil if it is always printed.
+	shown func() bool
+	// shownFlag is the flag which must be used for the column to be printed, if shown isn't nil.
+	shownFlag string
+	// total is the column's overall total, which is used by -p.
+	total float64
+}
+
+// columns contains the columns of loc's output in the order they are printed.
+var columns = []*column{
+	{
+		name:      "loc",
+		dirCounts: func(d *directory) map[string]int { return d.locCounts },
+		fileCount: func(f *file) int { return f.loc },
+		format:    addCommas,
+	},
+	{
+		name:      "lloc",
+		dirCounts: func(d *directory) map[string]int { return d.logicalCounts },
+		fileCount: func(f *file) int { return f.logicalLoc },
+		format:    addCommas,
+		shown:     func() bool { return *logicalFlag },
+		shownFlag: "-ll",
+	},
+	{
+		name:      "uloc",
+		dirCounts: func(d *directory) map[string]int { return d.uniqueCounts },
+		fileCount: func(f *file) int { return f.uniqueLoc },
+		// lines can be shared between languages, so the total can be less than the sum of the counts
+		dirTotal:  func(d *directory) int { return d.uniqueTotal },
+		format:    addCommas,
+		noPercent: true,
+		shown:     func() bool { return *uniqueFlag },
+		shownFlag: "-u",
+	},
+	{
+		name:      "testloc",
+		dirCounts: func(d *directory) map[string]int { return d.testCounts },
+		fileCount: func(f *file) int {
+			if f.test {
+				return f.loc
+			}
+			return 0
+		},
+		format:    addCommas,
+		shown:     showTests,
+		shownFlag: "--tests",
+	},
+	{
+		name: "prodloc",
+		dirCounts: func(d *directory) map[string]int {
+			prodLoc := make(map[string]int, len(d.locCounts))
+			for language, loc := range d.locCounts {
+				prodLoc[language] = loc - d.testCounts[language]
+			}
+			return prodLoc
+		},
+		fileCount: func(f *file) int {
+			if f.test {
+				return 0
+			}
+			return f.loc
+		},
+		format:    addCommas,
+		shown:     showTests,
+		shownFlag: "--tests",
+	},
+	{
+		name: "testratio",
+		dirCounts: func(d *directory) map[string]int {
+			ratios := make(map[string]int, len(d.locCounts))
+			for language, loc := range d.locCounts {
+				ratios[language] = testRatio(d.testCounts[language], loc)
+			}
+			return ratios
+		},
+		dirTotal: func(d *directory) int {
+			return testRatio(sumMapValues(d.testCounts), sumMapValues(d.locCounts))
+		},
+		format:    formatRatio,
+		noPercent: true,
+		shown:     showTests,
+		shownFlag: "--tests",
+	},
+	{
+		name:      "generated",
+		dirCounts: func(d *directory) map[string]int { return d.generatedCounts },
+		fileCount: func(f *file) int { return f.generatedLoc },
+		format:    addCommas,
+	},
+	{
+		name:      "comments",
+		dirCounts: func(d *directory) map[string]int { return d.commentCounts },
+		fileCount: func(f *file) int { return f.comments },
+		format:    addCommas,
+	},
+	{
+		name:      "docs",
+		dirCounts: func(d *directory) map[string]int { return d.docCounts },
+		fileCount: func(f *file) int { return f.docs },
+		format:    addCommas,
+	},
+	{
+		name:      "mixed",
+		dirCounts: func(d *directory) map[string]int { return d.mixedCounts },
+		fileCount: func(f *file) int { return f.mixed },
+		format:    addCommas,
+	},
+	{
+		name:      "blanks",
+		dirCounts: func(d *directory) map[string]int { return d.blankCounts },
+		fileCount: func(f *file) int { return f.blanks },
+		format:    addCommas,
+	},
+	{
+		name:      "complexity",
+		dirCounts: func(d *directory) map[string]int { return d.complexityCounts },
+		fileCount: func(f *file) int { return f.complexity },
+		format:    addCommas,
+	},
+	{
+		name:      "markers",
+		dirCounts: func(d *directory) map[string]int { return d.markerCounts },
+		fileCount: func(f *file) int { return f.markers },
+		format:    addCommas,
+		shown:     func() bool { return *printMarkersFlag },
+		shownFlag: "-t",
+	},
+	{
+		name:      "maxlen",
+		dirCounts: func(d *directory) map[string]int { return d.maxLineLengths },
+		fileCount: func(f *file) int { return f.maxLineLength },
+		dirTotal:  func(d *directory) int { return maxMapValue(d.maxLineLengths) },
+		format:    addCommas,
+		noPercent: true,
+		shown:     showLineLengths,
+		shownFlag: "-w",
+	},
+	{
+		name: "meanlen",
+		dirCounts: func(d *directory) map[string]int {
+			means := make(map[string]int, len(d.lineLengthTotals))
+			for language, total := range d.lineLengthTotals {
+				means[language] = safeDivide(total, d.nonBlankCounts[language])
+			}
+			return means
+		},
+		fileCount: func(f *file) int { return safeDivide(f.lineLengthTotal, f.nonBlankLines) },
+		dirTotal: func(d *directory) int {
+			return safeDivide(sumMapValues(d.lineLengthTotals), sumMapValues(d.nonBlankCounts))
+		},
+		format:    addCommas,
+		noPercent: true,
+		shown:     showLineLengths,
+		shownFlag: "-w",
+	},
+	{
+		name:      "longlines",
+		dirCounts: func(d *directory) map[string]int { return d.longLineCounts },
+		fileCount: func(f *file) int { return f.longLines },
+		format:    addCommas,
+		shown:     showLineLengths,
+		shownFlag: "-w",
+	},
+	{
+		name:      "size",
+		dirCounts: func(d *directory) map[string]int { return d.byteCounts },
+		fileCount: func(f *file) int { return f.bytes },
+		format:    formatByteCount,
+	},
+	{
+		name:      "files",
+		dirCounts: func(d *directory) map[string]int { return d.fileCounts },
+		format:    addCommas,
+	},
+}
+
+// showLineLengths reports whether the line length columns are printed, which requires -w.
+func showLineLengths() bool {
+	return *lineWidth > 0
+}
+
+// showTests reports whether the test columns are printed, which requires --tests.
+func showTests() bool {
+	return *testsFlag
+}
+
+// shownColumns returns the columns which are printed.
+func shownColumns() []*column {
+	var result []*column
+	for _, c := range columns {
+		if c.isShown() {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// columnNames returns the names of the printed columns, or only those printed by file if byFile is true.
+func columnNames(byFile bool) []string {
+	var names []string
+	for _, c := range shownColumns() {
+		if !byFile || c.fileCount != nil {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// findColumn returns the column with the given name, or nil if there isn't one.
+func findColumn(name string) *column {
+	for _, c := range columns {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// isShown reports whether c is printed.
+func (c *column) isShown() bool {
+	return c.shown == nil || c.shown()
+}
+
+// dirTotalCount returns c's total across all languages for d.
+func (c *column) dirTotalCount(d *directory) int {
+	if c.dirTotal != nil {
+		return c.dirTotal(d)
+	}
+	return sumMapValues(c.dirCounts(d))
+}
+
+// formatCount converts a count into a string, as a percentage of c's total if percent is true.
+func (c *column) formatCount(count int, percent bool) string {
+	if !percent || c.noPercent {
+		return c.format(count)
+	}
+	// avoid printing NaN for columns with no counts
+	if c.total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(count)/c.total*100)
+}
+
+// formatDirCounts formats d's counts for the given language in each column.
+func formatDirCounts(d *directory, language string, percent bool) string {
+	var values []string
+	for _, c := range shownColumns() {
+		values = append(values, c.formatCount(c.dirCounts(d)[language], percent))
+	}
+	return strings.Join(values, " | ")
+}
+
+// formatDirTotals formats d's totals across all languages in each column.
+func formatDirTotals(d *directory, percent bool) string {
+	var values []string
+	for _, c := range shownColumns() {
+		values = append(values, c.formatCount(c.dirTotalCount(d), percent))
+	}
+	return strings.Join(values, " | ")
+}
+
+// formatFileCounts formats f's counts in each column that is printed by file.
+func formatFileCounts(f *file, percent bool) string {
+	var values []string
+	for _, c := range shownColumns() {
+		if c.fileCount != nil {
+			values = append(values, c.formatCount(c.fileCount(f), percent))
+		}
+	}
+	return strings.Join(values, " | ")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -25,8 +26,31 @@ type directory struct {
 	subdirectories []*directory
 	files          []*file
 	locCounts      map[string]int
-	fileCounts     map[string]int
-	byteCounts     map[string]int
+	logicalCounts  map[string]int
+	// testCounts contains the loc in test files, the rest of which is production loc.
+	testCounts       map[string]int
+	generatedCounts  map[string]int
+	commentCounts    map[string]int
+	docCounts        map[string]int
+	mixedCounts      map[string]int
+	blankCounts      map[string]int
+	complexityCounts map[string]int
+	markerCounts     map[string]int
+	maxLineLengths   map[string]int
+	lineLengthTotals map[string]int
+	longLineCounts   map[string]int
+	// nonBlankCounts contains the numbers of non-blank lines, which are used for mean line lengths.
+	nonBlankCounts map[string]int
+	/*
+		uniqueLines contains the hashes of the distinct lines in each language, which are released once they are
+		added to the parent directory's. uniqueCounts and uniqueTotal are the numbers of unique lines by language
+		and across all languages, which are calculated from uniqueLines by countDirLoc.
+	*/
+	uniqueLines  map[string]map[uint64]bool
+	uniqueCounts map[string]int
+	uniqueTotal  int
+	fileCounts   map[string]int
+	byteCounts   map[string]int
 }
 
 // searchDir indexes d's files and subdirectories.
@@ -78,16 +102,7 @@ func (d *directory) searchDir() {
 				}
 			}
 		} else if d.countLoc {
-			fileExt := strings.TrimPrefix(filepath.Ext(entryName), ".")
-			// determine file's language by its name, which takes precedence over extension
-			fileLang, isCode := fileNames[entryName]
-			if !isCode {
-				// determine file's language by its extension
-				fileLang, isCode = extensions[fileExt]
-			}
-			if !isCode {
-				continue
-			}
+			fileExt := fileExtension(entryName)
 
 			var skipFile bool
 			// check for matches with included/excluded extensions
@@ -100,16 +115,6 @@ func (d *directory) searchDir() {
 				continue
 			}
 
-			// check for matches with included/excluded languages
-			if len(includeLangs) > 0 {
-				skipFile = !slices.Contains(includeLangs, fileLang)
-			} else {
-				skipFile = slices.Contains(excludeLangs, fileLang)
-			}
-			if skipFile {
-				continue
-			}
-
 			// check for matches with included/excluded files
 			if len(includeFiles) > 0 {
 				skipFile = true
@@ -131,15 +136,24 @@ func (d *directory) searchDir() {
 				continue
 			}
 
-			// process files concurrently
+			// process files concurrently, including language detection, which may read the file
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
 				semaphore <- struct{}{}
 				defer func() { <-semaphore }()
 
+				fileLang, isCode, data := detectLanguage(fullPath, entryName)
+				filtered := languageFiltered(fileLang)
+				if !isCode || (filtered && !splitsEmbedded(fileLang)) {
+					return
+				}
+
 				size := info.Size()
-				file := newFile(fullPath, fileLang, size)
+				file, ok := newFile(fullPath, fileLang, size, data)
+				if !ok || (filtered && !file.hasUnfilteredEmbedded()) {
+					return
+				}
 				mu.Lock()
 				d.files = append(d.files, file)
 				mu.Unlock()
@@ -147,20 +161,76 @@ func (d *directory) searchDir() {
 		}
 	}
 	wg.Wait()
+
+	if *dedupFlag {
+		d.files = removeDuplicates(d.files)
+	}
 }
 
 // countDirLoc counts the lines of code for each language in all indexed files.
 func (d *directory) countDirLoc() {
 	for _, file := range d.files {
-		d.locCounts[file.language] += file.loc
-		d.fileCounts[file.language]++
-		d.byteCounts[file.language] += file.bytes
+		// files in a filtered language are only kept for their embedded code
+		if !languageFiltered(file.language) {
+			d.addLineCounts(file)
+			d.fileCounts[file.language]++
+			d.byteCounts[file.language] += file.bytes
+		}
+		// embedded code is counted in its own language, but not as files
+		for _, embedded := range file.embedded {
+			if !languageFiltered(embedded.language) {
+				d.addLineCounts(embedded)
+			}
+		}
 	}
 
 	for _, subdir := range d.subdirectories {
 		for fileType, loc := range subdir.locCounts {
 			d.locCounts[fileType] += loc
 		}
+		for fileType, n := range subdir.logicalCounts {
+			d.logicalCounts[fileType] += n
+		}
+		for fileType, n := range subdir.testCounts {
+			d.testCounts[fileType] += n
+		}
+		for fileType, n := range subdir.generatedCounts {
+			d.generatedCounts[fileType] += n
+		}
+		for fileType, n := range subdir.commentCounts {
+			d.commentCounts[fileType] += n
+		}
+		for fileType, n := range subdir.docCounts {
+			d.docCounts[fileType] += n
+		}
+		for fileType, n := range subdir.mixedCounts {
+			d.mixedCounts[fileType] += n
+		}
+		for fileType, n := range subdir.blankCounts {
+			d.blankCounts[fileType] += n
+		}
+		for fileType, n := range subdir.complexityCounts {
+			d.complexityCounts[fileType] += n
+		}
+		for fileType, n := range subdir.markerCounts {
+			d.markerCounts[fileType] += n
+		}
+		for fileType, n := range subdir.maxLineLengths {
+			d.maxLineLengths[fileType] = max(d.maxLineLengths[fileType], n)
+		}
+		for fileType, n := range subdir.lineLengthTotals {
+			d.lineLengthTotals[fileType] += n
+		}
+		for fileType, n := range subdir.longLineCounts {
+			d.longLineCounts[fileType] += n
+		}
+		for fileType, n := range subdir.nonBlankCounts {
+			d.nonBlankCounts[fileType] += n
+		}
+		for fileType, lines := range subdir.uniqueLines {
+			mergeLineSet(d.uniqueLines, fileType, lines)
+		}
+		subdir.uniqueLines = nil
 		for fileType, n := range subdir.fileCounts {
 			d.fileCounts[fileType] += n
 		}
@@ -168,6 +238,31 @@ func (d *directory) countDirLoc() {
 			d.byteCounts[fileType] += b
 		}
 	}
+
+	d.countUniqueLines()
+}
+
+// addLineCounts adds the line counts of f to d's counts for f's language.
+func (d *directory) addLineCounts(f *file) {
+	d.locCounts[f.language] += f.loc
+	d.logicalCounts[f.language] += f.logicalLoc
+	if f.test {
+		d.testCounts[f.language] += f.loc
+	}
+	d.generatedCounts[f.language] += f.generatedLoc
+	d.commentCounts[f.language] += f.comments
+	d.docCounts[f.language] += f.docs
+	d.mixedCounts[f.language] += f.mixed
+	d.blankCounts[f.language] += f.blanks
+	d.complexityCounts[f.language] += f.complexity
+	d.markerCounts[f.language] += f.markers
+	d.maxLineLengths[f.language] = max(d.maxLineLengths[f.language], f.maxLineLength)
+	d.lineLengthTotals[f.language] += f.lineLengthTotal
+	d.longLineCounts[f.language] += f.longLines
+	d.nonBlankCounts[f.language] += f.nonBlankLines
+	f.uniqueLoc = len(f.uniqueLines)
+	mergeLineSet(d.uniqueLines, f.language, f.uniqueLines)
+	f.uniqueLines = nil
 }
 
 // printTreeLoc prints loc in d and its tree as specified by flags.
@@ -184,11 +279,15 @@ func (d *directory) printTreeLoc() {
 
 		indent := strings.Repeat("    ", d.parents+1)
 		if !fileHeadersPrinted && len(files) > 0 {
-			fmt.Printf("\033[1m%sloc | size - file\033[0m\n", indent)
+			headers := columnNames(true)
+			if *lineEndingsFlag {
+				headers = append(headers, "endings")
+			}
+			fmt.Printf("\033[1m%s%s - file\033[0m\n", indent, strings.Join(headers, " | "))
 			fileHeadersPrinted = true
 		}
 
-		for i, file := range sortFiles(files, *sortColumn) {
+		for i, file := range sortFiles(files, findColumn(*sortColumn)) {
 			if i+1 > *maxFilesPrint {
 				break
 			}
@@ -208,37 +307,34 @@ func (d *directory) printTreeLoc() {
 				fileName = strings.Replace(file.fullPath, d.fullPath, "", 1)
 			}
 
-			if *percentagesFlag {
-				fmt.Printf(
-					"%s%.1f%% | %.1f%% - %s\n",
-					indent,
-					float64(file.loc)/totalLoc*100,
-					float64(file.bytes)/totalBytes*100,
-					strings.TrimLeft(fileName, pathSeparator),
-				)
-			} else {
-				fmt.Printf(
-					"%s%s | %s - %s\n",
-					indent,
-					addCommas(file.loc),
-					formatByteCount(file.bytes),
-					strings.TrimLeft(fileName, pathSeparator),
-				)
+			counts := formatFileCounts(file, *percentagesFlag)
+			if *lineEndingsFlag {
+				counts += " | " + file.lineEndings()
+			}
+			fileName = strings.TrimLeft(fileName, pathSeparator)
+			if !languageFiltered(file.language) {
+				fmt.Printf("%s%s - %s\n", indent, counts, fileName)
+			}
+
+			// print embedded code below its file, labeled with its language
+			for _, lang := range slices.Sorted(maps.Keys(file.embedded)) {
+				if languageFiltered(lang) {
+					continue
+				}
+				counts = formatFileCounts(file.embedded[lang], *percentagesFlag)
+				if *lineEndingsFlag {
+					counts += " | " + file.lineEndings()
+				}
+				fmt.Printf("%s%s - %s [%s]\n", indent, counts, fileName, lang)
 			}
 		}
 	}
 
 	if d.printSubdirs {
 		// sort the subdirectories by the selected sort column
+		sortBy := findColumn(*sortColumn)
 		sort.Slice(d.subdirectories, func(i, j int) bool {
-			switch *sortColumn {
-			case "size":
-				return sumMapValues(d.subdirectories[i].byteCounts) > sumMapValues(d.subdirectories[j].byteCounts)
-			case "files":
-				return sumMapValues(d.subdirectories[i].fileCounts) > sumMapValues(d.subdirectories[j].fileCounts)
-			default:
-				return sumMapValues(d.subdirectories[i].locCounts) > sumMapValues(d.subdirectories[j].locCounts)
-			}
+			return sortBy.dirTotalCount(d.subdirectories[i]) > sortBy.dirTotalCount(d.subdirectories[j])
 		})
 
 		for _, subdir := range d.subdirectories {
@@ -262,63 +358,33 @@ func (d *directory) printLocSummary() {
 
 	// print column labels on first directory
 	if d.parents == 0 {
-		fmt.Printf("\033[1m%sLanguage: loc | size | files\033[0m\n", indent)
+		fmt.Printf("\033[1m%sLanguage: %s\033[0m\n", indent, strings.Join(columnNames(false), " | "))
 	}
 
 	// print loc total if multiple languages are present
 	if len(d.locCounts) > 1 {
-		if *percentagesFlag && d.parents > 0 {
-			fmt.Printf(
-				"%s%d langs: %.1f%% | %.1f%% | %.1f%%\n",
-				indent, len(d.locCounts),
-				float64(sumMapValues(d.locCounts))/totalLoc*100,
-				float64(sumMapValues(d.byteCounts))/totalBytes*100,
-				float64(sumMapValues(d.fileCounts))/totalFiles*100,
-			)
-		} else {
-			fmt.Printf(
-				"%s%d langs: %s | %s | %s\n",
-				indent, len(d.locCounts),
-				addCommas(sumMapValues(d.locCounts)),
-				formatByteCount(sumMapValues(d.byteCounts)),
-				addCommas(sumMapValues(d.fileCounts)),
-			)
-		}
+		fmt.Printf(
+			"%s%d langs: %s\n",
+			indent, len(d.locCounts),
+			formatDirTotals(d, *percentagesFlag && d.parents > 0),
+		)
 	}
 
-	// keys contains the file type keys sorted by their sortColumn values.
-	var keys []string
-	switch *sortColumn {
-	case "size":
-		keys = sortKeys(d.byteCounts)
-	case "files":
-		keys = sortKeys(d.fileCounts)
-	default:
-		keys = sortKeys(d.locCounts)
-	}
+	// keys contains the file type keys sorted by their sortColumn values. They're taken from locCounts because
+	// languages found only in embedded code have no files or size, so they're missing from those columns' counts.
+	keys := slices.Collect(maps.Keys(d.locCounts))
+	quickSort(findColumn(*sortColumn).dirCounts(d), keys, 0, len(keys)-1)
 	// print loc totals by file type
 	for i, fileType := range keys {
 		// print language total even if -ml=0 if there's only one language
 		if i+1 > *maxTotalsPrint && len(d.locCounts) > 1 {
 			break
 		}
-		if *percentagesFlag && !(len(d.locCounts) == 1 && d.parents == 0) {
-			fmt.Printf(
-				"%s%s: %.1f%% | %.1f%% | %.1f%%\n",
-				indent, fileType,
-				float64(d.locCounts[fileType])/totalLoc*100,
-				float64(d.byteCounts[fileType])/totalBytes*100,
-				float64(d.fileCounts[fileType])/totalFiles*100,
-			)
-		} else {
-			fmt.Printf(
-				"%s%s: %s | %s | %s\n",
-				indent, fileType,
-				addCommas(d.locCounts[fileType]),
-				formatByteCount(d.byteCounts[fileType]),
-				addCommas(d.fileCounts[fileType]),
-			)
-		}
+		fmt.Printf(
+			"%s%s: %s\n",
+			indent, fileType,
+			formatDirCounts(d, fileType, *percentagesFlag && !(len(d.locCounts) == 1 && d.parents == 0)),
+		)
 	}
 }
 
@@ -339,6 +405,27 @@ func (d *directory) decrementParents() {
 	}
 }
 
+// initCounts makes d's count maps, which must be done before any counts are added to them.
+func (d *directory) initCounts() {
+	d.locCounts = make(map[string]int)
+	d.logicalCounts = make(map[string]int)
+	d.testCounts = make(map[string]int)
+	d.generatedCounts = make(map[string]int)
+	d.commentCounts = make(map[string]int)
+	d.docCounts = make(map[string]int)
+	d.mixedCounts = make(map[string]int)
+	d.blankCounts = make(map[string]int)
+	d.complexityCounts = make(map[string]int)
+	d.markerCounts = make(map[string]int)
+	d.maxLineLengths = make(map[string]int)
+	d.lineLengthTotals = make(map[string]int)
+	d.longLineCounts = make(map[string]int)
+	d.nonBlankCounts = make(map[string]int)
+	d.uniqueLines = make(map[string]map[uint64]bool)
+	d.fileCounts = make(map[string]int)
+	d.byteCounts = make(map[string]int)
+}
+
 // newDirectory is the constructor for instances of the directory struct.
 func newDirectory(path string, numParents int, parentCountLoc bool) (*directory, bool) {
 	self := &directory{
@@ -346,10 +433,8 @@ func newDirectory(path string, numParents int, parentCountLoc bool) (*directory,
 		parents:       numParents,
 		compressLevel: 1,
 		printSubdirs:  numParents+1 <= *maxPrintDepth,
-		locCounts:     make(map[string]int),
-		fileCounts:    make(map[string]int),
-		byteCounts:    make(map[string]int),
 	}
+	self.initCounts()
 
 	// check whether files should be counted according to includeDirs
 	if len(includeDirs) == 0 || parentCountLoc {
@@ -378,5 +463,5 @@ func newDirectory(path string, numParents int, parentCountLoc bool) (*directory,
 		child.decrementParents() // to avoid extra indenting
 		return child, true
 	}
-	return self, len(self.fileCounts) != 0
+	return self, len(self.locCounts) != 0
 }
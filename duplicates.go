@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+/*
+filesByHash maps the content hashes of counted files to the files with those contents,
+the first of which is the only one counted. It is only used if --dedup is used.
+*/
+var filesByHash = make(map[[32]byte][]*file)
+
+// removeDuplicates removes the files from the input slice whose contents match a previously counted file.
+func removeDuplicates(files []*file) []*file {
+	// process files in a consistent order, since they are read concurrently
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].fullPath < files[j].fullPath
+	})
+
+	var result []*file
+	for _, f := range files {
+		// empty files (e.g. Python's __init__.py files) are identical by nature rather than copies,
+		// so they're always counted
+		if f.bytes == 0 || (f.nonBlankLines+f.blanks == 0 && f.embedded == nil) {
+			result = append(result, f)
+			continue
+		}
+		_, seen := filesByHash[f.hash]
+		filesByHash[f.hash] = append(filesByHash[f.hash], f)
+		if !seen {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// printDuplicates prints each group of identical files and the loc that wasn't counted from them.
+func printDuplicates() {
+	// groups contains the groups of identical files with at least one duplicate.
+	var groups [][]*file
+	var totalFiles, totalLoc int
+	for _, files := range filesByHash {
+		if len(files) > 1 {
+			groups = append(groups, files)
+			totalFiles += len(files) - 1
+			totalLoc += (len(files) - 1) * files[0].loc
+		}
+	}
+	if len(groups) == 0 {
+		return
+	}
+
+	// print the groups which would add the most loc first
+	sort.Slice(groups, func(i, j int) bool {
+		iLoc, jLoc := (len(groups[i])-1)*groups[i][0].loc, (len(groups[j])-1)*groups[j][0].loc
+		if iLoc != jLoc {
+			return iLoc > jLoc
+		}
+		return groups[i][0].fullPath < groups[j][0].fullPath
+	})
+
+	fmt.Printf(
+		"\033[1mDuplicates: %s files, %s loc not counted\033[0m\n",
+		addCommas(totalFiles), addCommas(totalLoc),
+	)
+	for _, files := range groups {
+		hash := hex.EncodeToString(files[0].hash[:])
+		fmt.Printf(
+			"    %s: %s copies, %s loc not counted\n",
+			hash[:12], addCommas(len(files)), addCommas((len(files)-1)*files[0].loc),
+		)
+		for i, f := range files {
+			path := relToCwd(f.fullPath)
+			if i == 0 {
+				path += " (counted)"
+			}
+			fmt.Printf("        %s\n", path)
+		}
+	}
+}
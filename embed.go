@@ -0,0 +1,297 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// rstDirective matches reStructuredText code directives, capturing their indentation and language.
+	rstDirective = regexp.MustCompile(`^(\s*)\.\.\s+(?:code-block|code|sourcecode)::\s*(\S*)`)
+	// asciidocSource matches AsciiDoc source block attributes (e.g. "[source,python]"), capturing their language.
+	asciidocSource = regexp.MustCompile(`^\[(?:source)?,\s*([^,\]\s]+)`)
+	// asciidocListing matches AsciiDoc listing block delimiters.
+	asciidocListing = regexp.MustCompile(`^-{4,}$`)
+
+	// htmlSectionTag matches the opening tags of script and style elements, capturing their names and attributes.
+	htmlSectionTag = regexp.MustCompile(`(?i)<(script|style)\b([^>]*)>`)
+	// htmlSectionEnds match the closing tags of script and style elements.
+	htmlSectionEnds = map[string]*regexp.Regexp{
+		"script": regexp.MustCompile(`(?i)</script\s*>`),
+		"style":  regexp.MustCompile(`(?i)</style\s*>`),
+	}
+	// htmlAttribute matches the lang and type attributes of tags, capturing their names and values.
+	htmlAttribute = regexp.MustCompile(`(?i)\b(lang|type)\s*=\s*["']?([^"'\s>]+)`)
+	// phpStart matches the opening tags of PHP code (but not XML declarations, which also begin with "<?").
+	phpStart = regexp.MustCompile(`(?i)<\?(?:php\b|=)`)
+	// phpEnd matches the closing tags of PHP code.
+	phpEnd = regexp.MustCompile(`\?>`)
+)
+
+/*
+splitter finds the lines of code embedded in a file written in another language,
+such as the fenced code blocks in a Markdown file.
+*/
+type splitter interface {
+	/*
+		split returns the language of the code embedded in a line (without its line ending), or "" if the line
+		belongs to the file's own language. start is true for the first line of each block of embedded code.
+	*/
+	split(line string) (lang string, start bool)
+}
+
+/*
+newSplitter returns a splitter for files in the given language, or nil if there isn't one. HTML-like files
+are always split, while code blocks in documentation are only split if --embed is used.
+*/
+func newSplitter(lang string) splitter {
+	switch lang {
+	case "Markdown", "ReStructuredText", "AsciiDoc":
+		if !*embedFlag {
+			return nil
+		}
+	}
+
+	switch lang {
+	case "Markdown":
+		return &markdownSplitter{}
+	case "ReStructuredText":
+		return &rstSplitter{}
+	case "AsciiDoc":
+		return &asciidocSplitter{}
+	case "HTML", "Svelte", "Vue":
+		return &htmlSplitter{php: true}
+	case "PHP":
+		return &htmlSplitter{}
+	}
+	return nil
+}
+
+/*
+splitsEmbedded reports whether files in the given language are split into embedded code, which is filtered by
+its own language. Such files are counted even if their own language is filtered, in case their embedded code isn't.
+*/
+func splitsEmbedded(lang string) bool {
+	return lang == notebookLanguage || newSplitter(lang) != nil
+}
+
+// hasUnfilteredEmbedded reports whether f contains embedded code in a language which isn't filtered.
+func (f *file) hasUnfilteredEmbedded() bool {
+	for lang := range f.embedded {
+		if !languageFiltered(lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownSplitter splits the fenced code blocks out of Markdown files.
+type markdownSplitter struct {
+	// fence contains the characters which opened the current code block, or "" if there isn't one.
+	fence string
+	// lang is the language of the current code block, or "" if it isn't recognized.
+	lang  string
+	start bool
+}
+
+func (s *markdownSplitter) split(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if s.fence == "" {
+		if fence, info, ok := parseFence(trimmed); ok {
+			s.fence, s.start = fence, true
+			s.lang, _ = languageFromName(info)
+		}
+		// fences are part of the Markdown
+		return "", false
+	}
+
+	// closing fences must be at least as long as the opening fence, with nothing after them
+	if strings.HasPrefix(trimmed, s.fence) && strings.Trim(trimmed, s.fence[:1]) == "" {
+		s.fence, s.lang = "", ""
+		return "", false
+	}
+	start := s.start
+	s.start = false
+	return s.lang, start
+}
+
+/*
+parseFence parses a line which opens a fenced code block, returning the fence characters and the
+first word of its info string (e.g. "```" and "python" for "```python title='x'"). ok is false if
+the line doesn't open a fenced code block.
+*/
+func parseFence(line string) (fence, info string, ok bool) {
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return "", "", false
+	}
+	length := len(line) - len(strings.TrimLeft(line, line[:1]))
+	fence, info = line[:length], strings.TrimSpace(line[length:])
+	// backtick fences can't contain backticks in their info string, since they would be inline code
+	if fence[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+
+	// info strings can also be attributes, as in "{.python .numberLines}"
+	info = strings.TrimLeft(info, "{.")
+	if end := strings.IndexAny(info, " \t,}"); end != -1 {
+		info = info[:end]
+	}
+	return fence, info, true
+}
+
+// rstSplitter splits the code directives (e.g. ".. code-block:: python") out of reStructuredText files.
+type rstSplitter struct {
+	// lang is the language of the current directive's code, or "" if there isn't one.
+	lang string
+	// indent is the indentation of the current directive, which its code must exceed.
+	indent    int
+	inContent bool
+}
+
+func (s *rstSplitter) split(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	if s.lang != "" {
+		if trimmed == "" {
+			if s.inContent {
+				return s.lang, false
+			}
+			return "", false
+		}
+		if indent > s.indent {
+			// options (e.g. ":linenos:") come before the code
+			if !s.inContent && strings.HasPrefix(trimmed, ":") {
+				return "", false
+			}
+			start := !s.inContent
+			s.inContent = true
+			return s.lang, start
+		}
+		// the directive has ended, and this line may begin another one
+		s.lang = ""
+	}
+
+	if match := rstDirective.FindStringSubmatch(line); match != nil {
+		s.lang, _ = languageFromName(match[2])
+		s.indent = len(match[1])
+		s.inContent = false
+	}
+	return "", false
+}
+
+// asciidocSplitter splits source blocks (e.g. "[source,python]" followed by a listing block) out of AsciiDoc files.
+type asciidocSplitter struct {
+	// markdown splits fenced code blocks, which AsciiDoc also supports.
+	markdown markdownSplitter
+	// pending is the language of the source attribute which applies to the next block.
+	pending string
+	// delimiter contains the characters which opened the current listing block, or "" if there isn't one.
+	delimiter string
+	lang      string
+	start     bool
+}
+
+func (s *asciidocSplitter) split(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if s.delimiter != "" {
+		if trimmed == s.delimiter {
+			s.delimiter, s.lang = "", ""
+			return "", false
+		}
+		start := s.start
+		s.start = false
+		return s.lang, start
+	}
+	if s.markdown.fence != "" {
+		return s.markdown.split(line)
+	}
+
+	if match := asciidocSource.FindStringSubmatch(trimmed); match != nil {
+		s.pending, _ = languageFromName(match[1])
+		return "", false
+	}
+	if asciidocListing.MatchString(trimmed) {
+		s.delimiter, s.lang, s.start = trimmed, s.pending, true
+		s.pending = ""
+		return "", false
+	}
+	// source attributes only apply to the block which follows them
+	if trimmed != "" {
+		s.pending = ""
+	}
+	return s.markdown.split(line)
+}
+
+/*
+htmlSplitter splits script and style elements out of HTML-like files (e.g. Vue single-file components),
+along with PHP code between "<?php" and "?>". Lines with opening or closing tags belong to the file's
+own language, as do elements which open and close on the same line.
+*/
+type htmlSplitter struct {
+	// php is whether PHP code is split out, which it isn't from PHP files.
+	php bool
+	// end matches the tag which closes the current section, or is nil if there isn't one.
+	end *regexp.Regexp
+	// lang is the language of the current section, or "" if it isn't recognized.
+	lang  string
+	start bool
+}
+
+func (s *htmlSplitter) split(line string) (string, bool) {
+	if s.end != nil {
+		if s.end.MatchString(line) {
+			s.end, s.lang = nil, ""
+			return "", false
+		}
+		start := s.start
+		s.start = false
+		return s.lang, start
+	}
+
+	if loc := htmlSectionTag.FindStringSubmatchIndex(line); loc != nil {
+		tag := strings.ToLower(line[loc[2]:loc[3]])
+		if end := htmlSectionEnds[tag]; !end.MatchString(line[loc[1]:]) {
+			s.end, s.lang, s.start = end, sectionLanguage(tag, line[loc[4]:loc[5]]), true
+		}
+	} else if s.php {
+		// only the last opening tag on the line can be left open
+		starts := phpStart.FindAllStringIndex(line, -1)
+		if len(starts) > 0 && !phpEnd.MatchString(line[starts[len(starts)-1][1]:]) {
+			s.end, s.start = phpEnd, true
+			s.lang, _ = languageFromName("php")
+		}
+	}
+	return "", false
+}
+
+/*
+sectionLanguage returns the language of a script or style element from its attributes, or "" if it isn't
+recognized. Scripts are JavaScript and styles are CSS unless their lang or type attributes say otherwise
+(e.g. lang="ts" or type="text/x-scss").
+*/
+func sectionLanguage(tag, attributes string) string {
+	name := "js"
+	if tag == "style" {
+		name = "css"
+	}
+	for _, match := range htmlAttribute.FindAllStringSubmatch(attributes, -1) {
+		value := strings.ToLower(match[2])
+		if strings.ToLower(match[1]) == "type" {
+			// module scripts are JavaScript
+			if value == "module" {
+				continue
+			}
+			// use the subtype of MIME types, as in "text/javascript" or "application/ld+json"
+			value = value[strings.LastIndexAny(value, "/+")+1:]
+			value = strings.TrimPrefix(value, "x-")
+		}
+		name = value
+	}
+
+	lang, ok := languageFromName(name)
+	if !ok {
+		return ""
+	}
+	return lang
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// textEncoding is the character encoding of a file's contents.
+type textEncoding int
+
+const (
+	utf8Encoding textEncoding = iota
+	utf16LEEncoding
+	utf16BEEncoding
+	utf32LEEncoding
+	utf32BEEncoding
+)
+
+// byteOrderMarks maps the encodings which can be detected by a byte order mark to their marks.
+var byteOrderMarks = []struct {
+	encoding textEncoding
+	mark     []byte
+}{
+	// UTF-32 marks are checked first because the UTF-32LE mark begins with the UTF-16LE mark
+	{utf32LEEncoding, []byte{0xFF, 0xFE, 0x00, 0x00}},
+	{utf32BEEncoding, []byte{0x00, 0x00, 0xFE, 0xFF}},
+	{utf8Encoding, []byte{0xEF, 0xBB, 0xBF}},
+	{utf16LEEncoding, []byte{0xFF, 0xFE}},
+	{utf16BEEncoding, []byte{0xFE, 0xFF}},
+}
+
+/*
+detectEncoding determines the encoding of a file from the start of its contents,
+returning the encoding and the length of its byte order mark, if it has one.
+Files without a byte order mark are checked for UTF-16, where text which is mostly ASCII has
+a NUL byte in every other position, and are otherwise assumed to be UTF-8.
+*/
+func detectEncoding(head []byte) (textEncoding, int) {
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(head, bom.mark) {
+			return bom.encoding, len(bom.mark)
+		}
+	}
+
+	// count NUL bytes in the even and odd positions of each two-byte unit
+	units := len(head) / 2
+	if units < 2 {
+		return utf8Encoding, 0
+	}
+	var evenNuls, oddNuls int
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenNuls++
+		}
+		if head[i+1] == 0 {
+			oddNuls++
+		}
+	}
+	if oddNuls*10 >= units*7 && evenNuls*10 < units {
+		return utf16LEEncoding, 0
+	} else if evenNuls*10 >= units*7 && oddNuls*10 < units {
+		return utf16BEEncoding, 0
+	}
+	return utf8Encoding, 0
+}
+
+// decodeText converts data from the given encoding into UTF-8.
+func decodeText(data []byte, encoding textEncoding) []byte {
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == utf16BEEncoding || encoding == utf32BEEncoding {
+		order = binary.BigEndian
+	}
+
+	var result []byte
+	switch encoding {
+	case utf16LEEncoding, utf16BEEncoding:
+		// a trailing odd byte is an incomplete character and is dropped
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[i*2:])
+		}
+		for _, r := range utf16.Decode(units) {
+			result = utf8.AppendRune(result, r)
+		}
+	case utf32LEEncoding, utf32BEEncoding:
+		for i := 0; i+3 < len(data); i += 4 {
+			// AppendRune writes invalid code points as the replacement character
+			result = utf8.AppendRune(result, rune(order.Uint32(data[i:])))
+		}
+	default:
+		result = data
+	}
+	return result
+}
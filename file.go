@@ -2,8 +2,35 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"io"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// sniffSize is the number of bytes at the start of a file which are checked for binary content.
+	sniffSize = 8_000
+	// maxLineSize is the length in bytes of the longest line which can be read from a file.
+	maxLineSize = 1_000_000_000
+	// generatedHeaderLines is the number of lines at the start of a file which are checked for generated code markers.
+	generatedHeaderLines = 10
+	// maxEscapeLength is the length of the longest escape sequence in a character literal, after its backslash.
+	maxEscapeLength = 10
+	// minifiedLineLength is the average length of a file's non-blank lines above which it is considered minified.
+	minifiedLineLength = 255
+)
+
+var (
+	// binaryFiles contains the paths of files skipped by countFileLoc because they are binary.
+	binaryFiles []string
+	// binaryFilesMu guards binaryFiles, which is appended to by concurrent file readers.
+	binaryFilesMu sync.Mutex
 )
 
 type file struct {
@@ -11,65 +38,752 @@ type file struct {
 	language string
 	bytes    int
 	loc      int
+	comments int
+	docs     int
+	mixed    int
+	blanks   int
+	// test is true if f is a test, which is only checked if --tests is used.
+	test bool
+	// logicalLoc is the number of statements in f's code.
+	logicalLoc int
+	// complexity is the number of complexity checks (e.g. "if ", "&& ") found in f's code.
+	complexity int
+	// binary is true if f's contents are not text, in which case its lines are not counted.
+	binary bool
+	// lfEndings, crlfEndings, and crEndings are the numbers of lines ending in "\n", "\r\n", and "\r".
+	lfEndings, crlfEndings, crEndings int
+	// generated is true if f is generated or minified, in which case its loc is counted in generatedLoc.
+	generated    bool
+	generatedLoc int
+	// maxLineLength, lineLengthTotal, and longLines are the line length statistics of f's non-blank lines.
+	maxLineLength   int
+	lineLengthTotal int
+	longLines       int
+	nonBlankLines   int
+	// markers is the number of markers (e.g. "TODO") in f's comments, which are listed in markerLines if -tl is used.
+	markers     int
+	markerLines []markerLine
+	// uniqueLines contains the hashes of f's distinct trimmed, non-blank lines, which are only stored if -u is used.
+	// They are released once they are added to f's directory, leaving their number in uniqueLoc.
+	uniqueLines map[uint64]bool
+	uniqueLoc   int
+	// hash is the SHA-256 hash of f's contents, which is only calculated if --dedup is used.
+	hash [32]byte
+	// embedded maps languages to the counts of code in f written in them, such as <script>s or notebook cells.
+	embedded map[string]*file
 }
 
-// countFileLoc counts the lines of code in f.
-func (f *file) countFileLoc() {
-	file, err := os.Open(f.fullPath)
-	if err != nil {
-		warn("Error opening file:", err)
-		return
-	}
-	defer func(file *os.File) {
-		err := file.Close()
+/*
+countFileLoc counts the lines of code, comments, documentation, mixed code and comments, and blank lines in f.
+data contains f's contents if they were already read in full, in which case the file isn't opened again.
+*/
+func (f *file) countFileLoc(data []byte) {
+	var contents io.Reader
+	if data != nil {
+		contents = bytes.NewReader(data)
+	} else {
+		file, err := os.Open(f.fullPath)
 		if err != nil {
-			warn("Error closing file:", err)
+			warn("Error opening file:", err)
+			return
 		}
-	}(file)
+		defer func(file *os.File) {
+			err := file.Close()
+			if err != nil {
+				warn("Error closing file:", err)
+			}
+		}(file)
+		contents = file
+	}
+
+	// hash the contents as they are read, if necessary
+	hasher := sha256.New()
+	if *dedupFlag {
+		contents = io.TeeReader(contents, hasher)
+	}
 
-	comChars, hasComments := singleLineCommentChars[f.language]
-	reader := bufio.NewReader(file)
-	var endOfFile, skipLine bool
-	for !endOfFile {
-		line, err := reader.ReadString('\n')
+	reader := bufio.NewReaderSize(contents, sniffSize)
+	// Peek returns an error for files shorter than sniffSize, but the bytes it returns are still valid
+	head, _ := reader.Peek(sniffSize)
+	encoding, bomSize := detectEncoding(head)
+	if encoding == utf8Encoding {
+		if isBinary(head[bomSize:]) {
+			f.binary = true
+			binaryFilesMu.Lock()
+			binaryFiles = append(binaryFiles, f.fullPath)
+			binaryFilesMu.Unlock()
+			return
+		}
+		// skip the byte order mark so that it isn't read as part of the first line
+		_, _ = reader.Discard(bomSize)
+	} else {
+		// files in other encodings are decoded in full, then read as UTF-8
+		data, err := io.ReadAll(reader)
 		if err != nil {
-			if err.Error() == "EOF" {
-				endOfFile = true
-			} else {
-				warn("Error reading line:", err)
-				continue
-			}
+			warn("Error reading file:", err)
+			return
 		}
-		line = strings.TrimSpace(line)
+		reader = bufio.NewReader(bytes.NewReader(decodeText(data[bomSize:], encoding)))
+	}
 
-		if line == "" {
-			continue
+	// notebooks are JSON, but only the contents of their cells are counted
+	if f.language == notebookLanguage {
+		f.countNotebook(reader)
+		if *dedupFlag {
+			copy(f.hash[:], hasher.Sum(nil))
 		}
+		return
+	}
 
-		if hasComments {
-			for _, char := range comChars {
-				if strings.HasPrefix(line, char) {
-					skipLine = true
-					break
+	classifier := newLineClassifier(f.language)
+	split := newSplitter(f.language)
+	// blockClassifier is the classifier for the current block of embedded code, if there is one.
+	var blockClassifier *lineClassifier
+
+	scanner := bufio.NewScanner(reader)
+	// allow for long lines, such as those in minified files
+	// small files don't need the default buffer, which would otherwise be allocated for every file
+	scanner.Buffer(make([]byte, 0, min(bufio.MaxScanTokenSize, f.bytes+1)), maxLineSize)
+	scanner.Split(scanLines)
+	// lineNum, nonBlankLines, and lineBytes are the numbers of lines and non-blank lines read so far,
+	// and the total length of the non-blank lines.
+	var lineNum, nonBlankLines, lineBytes int
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		if lineNum <= generatedHeaderLines && isGeneratedMarker(line) {
+			f.generated = true
+		}
+		// record the line ending, which scanLines leaves on the line
+		switch {
+		case strings.HasSuffix(line, "\r\n"):
+			f.crlfEndings++
+		case strings.HasSuffix(line, "\n"):
+			f.lfEndings++
+		case strings.HasSuffix(line, "\r"):
+			f.crEndings++
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// count embedded code in its own language
+		target, targetClassifier := f, classifier
+		if split != nil {
+			if lang, start := split.split(line); lang != "" {
+				if start || blockClassifier == nil {
+					blockClassifier = newLineClassifier(lang)
 				}
-			}
-			if skipLine {
-				skipLine = false
-				continue
+				target, targetClassifier = f.embeddedFile(lang), blockClassifier
 			}
 		}
 
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			nonBlankLines++
+			lineBytes += len(trimmed)
+		}
+		target.countLine(line, lineNum, targetClassifier)
+	}
+	if err := scanner.Err(); err != nil {
+		warn("Error reading line:", err)
+	}
+	if *dedupFlag {
+		copy(f.hash[:], hasher.Sum(nil))
+	}
+
+	// files with very long lines on average are minified
+	if nonBlankLines > 0 && lineBytes/nonBlankLines > minifiedLineLength {
+		f.generated = true
+	}
+	// humans didn't write the loc in generated files, so they're counted separately
+	if f.generated {
+		f.generatedLoc, f.loc, f.logicalLoc = f.loc, 0, 0
+		for _, embedded := range f.embedded {
+			embedded.generated = true
+			embedded.generatedLoc, embedded.loc, embedded.logicalLoc = embedded.loc, 0, 0
+		}
+	}
+}
+
+// countLine counts a line (without its line ending) in f's statistics, using c to classify it.
+func (f *file) countLine(line string, lineNum int, c *lineClassifier) {
+	// the length in characters of the line
+	length := utf8.RuneCountInString(line)
+	line = strings.TrimSpace(line)
+	if line == "" {
+		f.blanks++
+		return
+	}
+
+	f.nonBlankLines++
+	f.addUniqueLine(line)
+	f.maxLineLength = max(f.maxLineLength, length)
+	f.lineLengthTotal += length
+	if *lineWidth > 0 && length > *lineWidth {
+		f.longLines++
+	}
+
+	complexity, statements := c.complexity, c.statements
+	switch c.classify(line) {
+	case codeLine:
 		f.loc++
+	case commentLine:
+		f.comments++
+	case docLine:
+		f.docs++
+	case mixedLine:
+		f.mixed++
+		// count mixed lines towards loc and/or comments according to -mx
+		if *countMixedAs != "comment" {
+			f.loc++
+		}
+		if *countMixedAs != "code" {
+			f.comments++
+		}
+	}
+	f.complexity += c.complexity - complexity
+	f.countMarkers(c.comment, lineNum)
+	f.logicalLoc += c.statements - statements
+}
+
+// embeddedFile returns the file which contains the counts of f's code in the given language, creating it if necessary.
+func (f *file) embeddedFile(lang string) *file {
+	if f.embedded == nil {
+		f.embedded = make(map[string]*file)
+	}
+	embedded, ok := f.embedded[lang]
+	if !ok {
+		embedded = &file{fullPath: f.fullPath, language: lang}
+		f.embedded[lang] = embedded
 	}
+	return embedded
+}
+
+// isGeneratedMarker reports whether a line marks its file as generated, as in "Code generated by X. DO NOT EDIT.".
+func isGeneratedMarker(line string) bool {
+	return strings.Contains(line, "@generated") ||
+		strings.Contains(line, "Code generated") && strings.Contains(line, "DO NOT EDIT")
+}
+
+// lineEndings describes the line endings used in f.
+func (f *file) lineEndings() string {
+	var endings []string
+	if f.lfEndings > 0 {
+		endings = append(endings, "LF")
+	}
+	if f.crlfEndings > 0 {
+		endings = append(endings, "CRLF")
+	}
+	if f.crEndings > 0 {
+		endings = append(endings, "CR")
+	}
+
+	switch len(endings) {
+	case 0:
+		return "none"
+	case 1:
+		return endings[0]
+	}
+	return "mixed"
+}
+
+/*
+scanLines is a bufio.SplitFunc which splits lines ending in "\r\n", "\n", or a lone "\r".
+Unlike bufio.ScanLines, the line endings are included in the returned lines.
+*/
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	// find the first "\r" or "\n", using IndexByte since it's much faster than IndexAny
+	i := bytes.IndexByte(data, '\n')
+	search := data
+	if i != -1 {
+		search = data[:i]
+	}
+	if j := bytes.IndexByte(search, '\r'); j != -1 {
+		i = j
+	}
+	if i != -1 {
+		if data[i] == '\n' {
+			return i + 1, data[:i+1], nil
+		}
+		// a "\r" at the end of data may be the start of a "\r\n" ending
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i+2], nil
+		}
+		return i + 1, data[:i+1], nil
+	}
+
+	// the last line may not have a line ending
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+/*
+isBinary reports whether the start of a file's contents appears to be binary data.
+This is the case if it contains NUL bytes, or if much of it is not valid UTF-8, which allows
+for text files with a few characters in another encoding (e.g. Latin-1).
+*/
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) != -1 {
+		return true
+	}
+
+	var invalidBytes int
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 {
+			// the last rune may have been cut off by the end of head
+			if !utf8.FullRune(head[i:]) {
+				break
+			}
+			invalidBytes++
+		}
+		i += size
+	}
+	return invalidBytes*10 > len(head)*3
 }
 
 // newFile is the constructor for instances of the file struct.
-func newFile(path, lang string, size int64) *file {
+func newFile(path, lang string, size int64, data []byte) (*file, bool) {
 	self := &file{
 		fullPath: path,
 		language: lang,
 		bytes:    int(size),
 	}
-	self.countFileLoc()
+	self.countFileLoc(data)
+	if *testsFlag {
+		self.test = isTestFile(path, lang)
+		for _, embedded := range self.embedded {
+			embedded.test = self.test
+		}
+	}
+	return self, !self.binary && !(self.generated && *excludeGeneratedFlag)
+}
+
+// lineType is the classification of a non-blank line.
+type lineType int
+
+const (
+	codeLine lineType = iota
+	commentLine
+	docLine
+	// mixedLine is a line that contains both code and a comment.
+	mixedLine
+)
+
+/*
+docStringPrefixes maps languages to the attributes which can precede their doc strings.
+Any string literal which follows one of these is documentation, along with the attribute itself,
+and other string literals in these languages aren't.
+*/
+var docStringPrefixes = map[string][]string{
+	"Elixir": {"@moduledoc", "@typedoc", "@doc"},
+}
+
+/*
+docStringDefinitions maps languages to the patterns of the definitions whose doc strings follow them.
+In these languages, doc strings must be the first statement of a file or a definition.
+*/
+var docStringDefinitions = map[string]*regexp.Regexp{
+	"Python": regexp.MustCompile(`^(async\s+)?(def|class)\b`),
+}
+
+// quote contains the characters which start and end a string literal in a language.
+type quote struct {
+	start string
+	end   string
+	// ignoreEscape is true if backslashes do not escape characters in the literal (e.g. raw strings).
+	ignoreEscape bool
+	// docString is true if the literal is documentation when it makes up a statement by itself.
+	docString bool
+}
+
+// tokenKind is a set of the kinds of tokens which a byte can begin.
+type tokenKind uint8
+
+const (
+	blockCommentToken tokenKind = 1 << iota
+	lineCommentToken
+	quoteToken
+	docPrefixToken
+	charLiteralToken
+	checkToken
+)
+
+/*
+lineClassifier tracks the comment and string literal state of a file as its lines are read.
+Block comments and strings can span multiple lines, so each line must be classified in order.
+*/
+type lineClassifier struct {
+	lineComments  []string
+	blockComments [][2]string
+	nestedBlocks  [][2]string
+	quotes        []quote
+	docPrefixes   []string
+	definition    *regexp.Regexp
+	// checkStarts maps bytes to the complexity checks which begin with them, ignoring leading spaces
+	// (e.g. " func" is found at its "f"), so that spaces don't need to be checked.
+	checkStarts [256][]string
+	// tokenStarts contains the kinds of tokens which each byte can begin, so that other bytes of code
+	// can be skipped quickly.
+	tokenStarts [256]tokenKind
+	// buildCode is true if the text of code is needed to count statements or track doc strings,
+	// and buildComment is true if the text of comments is needed to find markers.
+	buildCode    bool
+	buildComment bool
+	// complexity is the number of complexity checks found in code so far.
+	complexity int
+	// openQuote is the quote of the open string literal, or nil if there isn't one.
+	openQuote *quote
+	// openDoc is true if the open string literal is a doc string.
+	openDoc bool
+	// block contains the start and end characters of the open block comment.
+	block [2]string
+	// nested is true if the open block comment is one of nestedBlocks.
+	nested bool
+	// blockDepth is the number of open block comments, which can only exceed 1 if nested is true.
+	blockDepth int
+	// rule is how the language's statements are separated, and statements is the number found in code so far.
+	rule       statementRule
+	statements int
+	// depth is the number of open parentheses and brackets in code.
+	depth int
+	// continued is true if the last statement continues onto the next line.
+	continued bool
+	// lastCode is the last non-whitespace character of code outside of comments and strings.
+	lastCode byte
+	// comment is the comment text of the last classified line.
+	comment string
+	// inDefinition is true if a definition's header (e.g. "def f(") has begun but not ended.
+	inDefinition bool
+	// docAllowed is true if a doc string can be the next statement, which is only tracked if definition isn't nil.
+	docAllowed bool
+}
+
+// classify returns the type of a trimmed, non-blank line, updating the comment and string state.
+func (c *lineClassifier) classify(line string) lineType {
+	var hasCode, hasDoc, hasComment bool
+	// code contains the line's code without comments, with each string literal replaced by a quotation mark
+	var code strings.Builder
+	var comment strings.Builder
+	for i := 0; i < len(line); {
+		rest := line[i:]
+
+		// inside a string literal, comment characters are part of the string
+		if c.openQuote != nil {
+			if c.openDoc {
+				hasDoc = true
+			} else {
+				hasCode = true
+			}
+			if strings.HasPrefix(rest, c.openQuote.end) {
+				i += len(c.openQuote.end)
+				c.openQuote = nil
+				c.openDoc = false
+			} else if line[i] == '\\' && !c.openQuote.ignoreEscape {
+				i += 2
+			} else {
+				// skip to the next byte which could end the string or escape its end
+				i++
+				for i < len(line) && line[i] != c.openQuote.end[0] && line[i] != '\\' {
+					i++
+				}
+			}
+			continue
+		}
+
+		// inside a block comment, only its start and end characters matter
+		if c.blockDepth > 0 {
+			hasComment = true
+			if strings.HasPrefix(rest, c.block[1]) {
+				i += len(c.block[1])
+				c.blockDepth--
+				// separate the text of consecutive comments
+				if c.buildComment {
+					comment.WriteByte(' ')
+				}
+			} else if c.nested && strings.HasPrefix(rest, c.block[0]) {
+				i += len(c.block[0])
+				c.blockDepth++
+			} else {
+				// skip to the next byte which could end the comment or open a nested one
+				j := i + 1
+				for j < len(line) && line[j] != c.block[1][0] && line[j] != c.block[0][0] {
+					j++
+				}
+				if c.buildComment {
+					comment.WriteString(line[i:j])
+				}
+				i = j
+			}
+			continue
+		}
+
+		// skip to the next byte which could begin a comment, string, or complexity check
+		kinds := c.tokenStarts[line[i]]
+		if kinds == 0 {
+			j := i
+			for ; j < len(line) && c.tokenStarts[line[j]] == 0; j++ {
+				if line[j] != ' ' && line[j] != '\t' {
+					hasCode = true
+				}
+			}
+			if c.buildCode {
+				code.WriteString(line[i:j])
+			}
+			i = j
+			continue
+		}
+
+		// check for the start of a block comment, which takes priority over line comments
+		// because some block comments begin with a line comment (e.g. Julia's "#=" and Lua's "--[[")
+		if kinds&blockCommentToken != 0 {
+			var opened bool
+			for _, pair := range c.blockComments {
+				if strings.HasPrefix(rest, pair[0]) {
+					i += len(pair[0])
+					c.block = pair
+					c.nested = slices.Contains(c.nestedBlocks, pair)
+					c.blockDepth = 1
+					opened = true
+					break
+				}
+			}
+			if opened {
+				continue
+			}
+		}
+
+		// the rest of the line is a comment
+		if kinds&lineCommentToken != 0 && hasAnyPrefix(rest, c.lineComments) {
+			hasComment = true
+			if c.buildComment {
+				comment.WriteString(rest)
+			}
+			break
+		}
+
+		// check for the start of a string literal
+		var afterPrefix bool
+		if i == 0 && kinds&docPrefixToken != 0 {
+			if start, ok := c.docStringStart(line); ok {
+				i, afterPrefix = start, true
+				rest = line[i:]
+				kinds = c.tokenStarts[line[i]]
+			}
+		}
+		var q *quote
+		if kinds&quoteToken != 0 {
+			q = c.matchQuote(rest)
+		}
+		if q != nil {
+			// a doc string must begin its line, possibly after a doc string prefix
+			c.openDoc = (afterPrefix || (q.docString && c.docStringAllowed())) && !hasCode
+			if c.openDoc {
+				hasDoc = true
+				c.docAllowed = false
+			} else {
+				hasCode = true
+				if c.buildCode {
+					code.WriteByte('"')
+				}
+			}
+			i += len(q.start)
+			c.openQuote = q
+			continue
+		}
+
+		// skip character literals (e.g. '"' in C), so that quotes inside them don't open strings
+		if kinds&charLiteralToken != 0 && (i == 0 || !isWordChar(line[i-1])) {
+			if n := charLiteralLength(rest); n > 0 {
+				hasCode = true
+				if c.buildCode {
+					code.WriteByte('"')
+				}
+				i += n
+				continue
+			}
+		}
+
+		// check for complexity checks, which must not begin mid-word unless they begin with a symbol
+		// (e.g. " func" or "&& ")
+		if kinds&checkToken != 0 && (i == 0 || !isWordChar(line[i]) || !isWordChar(line[i-1])) {
+			if check := matchCheck(line, i, c.checkStarts[line[i]]); check != "" {
+				c.complexity++
+				hasCode = true
+				if c.buildCode {
+					code.WriteString(check)
+				}
+				i += len(check)
+				continue
+			}
+		}
+
+		if line[i] != ' ' && line[i] != '\t' {
+			hasCode = true
+		}
+		if c.buildCode {
+			code.WriteByte(line[i])
+		}
+		i++
+	}
+	c.comment = comment.String()
+
+	if hasCode {
+		if c.buildCode {
+			c.countStatements(code.String())
+			c.trackDefinitions(strings.TrimSpace(code.String()))
+		}
+		// a doc string followed by code on the same line is an ordinary expression
+		c.openDoc = false
+		if hasComment {
+			return mixedLine
+		}
+		return codeLine
+	} else if hasDoc {
+		return docLine
+	}
+	return commentLine
+}
+
+/*
+docStringAllowed reports whether a string literal at the start of a line can be a doc string. It can't
+continue a statement (e.g. as a function's argument), and if the language has definition patterns,
+it must be the first statement of the file or of a definition. In languages with doc string prefixes,
+only strings which follow a prefix are doc strings (e.g. an Elixir heredoc containing SQL isn't).
+*/
+func (c *lineClassifier) docStringAllowed() bool {
+	if c.depth > 0 || c.continued || c.docPrefixes != nil {
+		return false
+	}
+	return c.definition == nil || c.docAllowed
+}
+
+// trackDefinitions updates whether a doc string can follow a line's code, if the language has definition patterns.
+func (c *lineClassifier) trackDefinitions(code string) {
+	if c.definition == nil {
+		return
+	}
+	if c.definition.MatchString(code) {
+		c.inDefinition = true
+	}
+	// definitions' headers end with a colon, possibly after multiple lines of parameters
+	c.docAllowed = c.inDefinition && c.depth == 0 && strings.HasSuffix(code, ":")
+	if c.docAllowed || (c.depth == 0 && !c.continued) {
+		c.inDefinition = false
+	}
+}
+
+// docStringStart returns the index after line's doc string prefix, if line has one followed by a string.
+func (c *lineClassifier) docStringStart(line string) (int, bool) {
+	for _, prefix := range c.docPrefixes {
+		if !strings.HasPrefix(line, prefix+" ") {
+			continue
+		}
+		start := len(line) - len(strings.TrimLeft(line[len(prefix):], " \t"))
+		if c.matchQuote(line[start:]) != nil {
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// matchQuote returns the longest quote which starts str, or nil if there isn't one.
+func (c *lineClassifier) matchQuote(str string) *quote {
+	var match *quote
+	for i, q := range c.quotes {
+		if strings.HasPrefix(str, q.start) && (match == nil || len(q.start) > len(match.start)) {
+			match = &c.quotes[i]
+		}
+	}
+	return match
+}
+
+/*
+charLiteralLength returns the length of the character literal (e.g. 'x' or '\n') which starts str,
+or 0 if there isn't one. Languages which use single quotes for strings match them as quotes first.
+*/
+func charLiteralLength(str string) int {
+	if len(str) < 3 || str[0] != '\'' || str[1] == '\'' {
+		return 0
+	}
+	if str[1] == '\\' {
+		// escape sequences can be several characters long (e.g. '\x7f' or '\u{1F600}')
+		end := strings.IndexByte(str[3:], '\'')
+		if end == -1 || end > maxEscapeLength {
+			return 0
+		}
+		return end + 4
+	}
+	_, size := utf8.DecodeRuneInString(str[1:])
+	if len(str) > size+1 && str[size+1] == '\'' {
+		return size + 2
+	}
+	return 0
+}
+
+/*
+matchCheck returns the complexity check which starts at index i of line, without its leading spaces
+(which precede i), or "" if there isn't one.
+*/
+func matchCheck(line string, i int, checks []string) string {
+	for _, check := range checks {
+		trimmed := strings.TrimLeft(check, " ")
+		lead := len(check) - len(trimmed)
+		if i >= lead && line[i-lead:i] == check[:lead] && strings.HasPrefix(line[i:], trimmed) {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// newLineClassifier is the constructor for instances of the lineClassifier struct.
+func newLineClassifier(lang string) *lineClassifier {
+	self := &lineClassifier{
+		lineComments:  singleLineCommentChars[lang],
+		blockComments: multiLineCommentChars[lang],
+		nestedBlocks:  nestedMultiLineComments[lang],
+		quotes:        quoteChars[lang],
+		docPrefixes:   docStringPrefixes[lang],
+		definition:    docStringDefinitions[lang],
+		docAllowed:    true,
+		rule:          statementRules[lang],
+	}
+	for _, check := range complexityChecks[lang] {
+		if trimmed := strings.TrimLeft(check, " "); trimmed != "" {
+			self.checkStarts[trimmed[0]] = append(self.checkStarts[trimmed[0]], check)
+			self.tokenStarts[trimmed[0]] |= checkToken
+		}
+	}
+	for _, chars := range self.lineComments {
+		if chars != "" {
+			self.tokenStarts[chars[0]] |= lineCommentToken
+		}
+	}
+	for _, pair := range self.blockComments {
+		if pair[0] != "" {
+			self.tokenStarts[pair[0][0]] |= blockCommentToken
+		}
+	}
+	for _, prefix := range self.docPrefixes {
+		self.tokenStarts[prefix[0]] |= docPrefixToken
+	}
+	self.tokenStarts['\''] |= charLiteralToken
+
+	// statements are counted for -ll, and tracked for doc strings, which can't continue a statement
+	self.buildCode = *logicalFlag || self.definition != nil
+	for _, q := range self.quotes {
+		if q.start != "" {
+			self.tokenStarts[q.start[0]] |= quoteToken
+		}
+		self.buildCode = self.buildCode || q.docString
+	}
+	self.buildComment = *printMarkersFlag || *listMarkersFlag
 	return self
 }
@@ -11,9 +11,24 @@ import (
 )
 
 var (
+	// cocomoOverhead is the value of the -co flag.
+	cocomoOverhead = flag.Float64("co", 2.4, "")
+
+	// cocomoFlag is the value of the --cocomo flag.
+	cocomoFlag = flag.Bool("cocomo", false, "")
+
+	// cocomoSalary is the value of the -cs flag.
+	cocomoSalary = flag.Int("cs", 56_286, "")
+
+	// cocomoType is the value of the -ct flag.
+	cocomoType = flag.String("ct", "organic", "")
+
 	// printDirFlag is the value of the -d flag.
 	printDirFlag = flag.Bool("d", false, "")
 
+	// dedupFlag is the value of the --dedup flag.
+	dedupFlag = flag.Bool("dedup", false, "")
+
 	// includeDotDirFlag is the value of the --dot flag.
 	includeDotDirFlag = flag.Bool("dot", false, "")
 
@@ -32,11 +47,17 @@ var (
 	// excludeFiles contains the parsed inputs for the -ef flag.
 	excludeFiles []string
 
+	// excludeGeneratedFlag is the value of the -eg flag.
+	excludeGeneratedFlag = flag.Bool("eg", false, "")
+
 	// excludeLangsFlag is the value of the -el flag.
 	excludeLangsFlag = flag.String("el", "", "")
 	// excludeLangs contains the parsed inputs for the -el flag.
 	excludeLangs []string
 
+	// embedFlag is the value of the --embed flag.
+	embedFlag = flag.Bool("embed", false, "")
+
 	// printFileFlag is the value of the -f flag.
 	printFileFlag = flag.Bool("f", false, "")
 
@@ -63,12 +84,21 @@ var (
 	// includeLangs contains the parsed inputs for the -il flag.
 	includeLangs []string
 
+	// lineEndingsFlag is the value of the -le flag.
+	lineEndingsFlag = flag.Bool("le", false, "")
+
+	// logicalFlag is the value of the -ll flag.
+	logicalFlag = flag.Bool("ll", false, "")
+
 	// maxFilesPrint is the value of the -mf flag.
 	maxFilesPrint = flag.Int("mf", 100_000, "")
 
 	// maxTotalsPrint is the value of the -ml flag.
 	maxTotalsPrint = flag.Int("ml", 1_000, "")
 
+	// countMixedAs is the value of the -mx flag.
+	countMixedAs = flag.String("mx", "code", "")
+
 	// percentagesFlag is the value of the -p flag.
 	percentagesFlag = flag.Bool("p", false, "")
 
@@ -84,6 +114,29 @@ var (
 	// maxSearchDepth is the value of the -sd flag.
 	maxSearchDepth = flag.Int("sd", 1_000, "")
 
+	// printMarkersFlag is the value of the -t flag.
+	printMarkersFlag = flag.Bool("t", false, "")
+
+	// listMarkersFlag is the value of the -tl flag.
+	listMarkersFlag = flag.Bool("tl", false, "")
+
+	// markersFlag is the value of the -tm flag.
+	markersFlag = flag.String("tm", "TODO,FIXME,HACK,XXX", "")
+	// markers contains the parsed inputs for the -tm flag.
+	markers []string
+
+	// testsFlag is the value of the --tests flag.
+	testsFlag = flag.Bool("tests", false, "")
+
+	// testPatternsFlag is the value of the -tp flag.
+	testPatternsFlag = flag.String("tp", "", "")
+
+	// uniqueFlag is the value of the -u flag.
+	uniqueFlag = flag.Bool("u", false, "")
+
+	// lineWidth is the value of the -w flag.
+	lineWidth = flag.Int("w", 0, "")
+
 	// licenseFlag is the value of the --license flag.
 	licenseFlag = flag.Bool("license", false, "")
 
@@ -124,7 +177,10 @@ SOFTWARE.`
 	// usageMessage is the output of usage().
 	usageMessage = `loc %s
 Count lines of code in directories and their subdirectories by language
-         Note: docstrings and multi-line comments are counted as lines of code
+         Note: standalone doc strings (e.g. Python docstrings) are counted as docs, not loc
+         Note: code in generated and minified files is counted as generated, not loc
+         Note: Jupyter notebook cells are counted in their languages, and their outputs are ignored
+         Note: <script>, <style>, and PHP sections of HTML-like files are counted in their languages
 
 Usage: loc [options] [dirs]
          Options must come before dirs
@@ -133,25 +189,45 @@ Usage: loc [options] [dirs]
          Dirs are the names/paths of directories to search (cwd by default)
 
 Options:
+        --cocomo   Print COCOMO estimates of the cost to develop the loc, by directory argument
+            -co <num>  Overhead multiplier of salaries (default: 2.4)
+            -cs <int>  Annual salary of developers in dollars (default: 56,286)
+            -ct <str>  Type of project ["organic", "semi-detached", "embedded"] (default: "organic")
         -d         Print loc by directory
             -pd <int>  Maximum depth of subdirectories to print (default: 1,000)
+        --dedup    Count identical files once and list the duplicates
         --dot      Include dot directories (excluded by default)
         -ed <str>  Directory trees to exclude (name or path, e.g. "node_modules,src/styles")
         -ee <str>  Extensions to exclude (e.g. "json,md,css")
         -ef <str>  Files to exclude (name or path, e.g. "README.md,vendor/htmx.js")
+        -eg        Exclude generated and minified files (counted as generated by default)
         -el <str>  Languages to exclude (e.g. "HTML,Plain Text,YAML")
+        --embed    Count code blocks in Markdown, reStructuredText, and AsciiDoc files in their languages
         -f         Print loc by file
+            -le        Print line endings by file ["LF", "CRLF", "CR", "mixed", "none"]
             -mf <int>  Maximum number of files to print per directory (default: 100,000)
         -fr <int>  Number of file-reading goroutines (default: %d)
         -id <str>  Directory trees to include, excluding others (name or path, e.g. "src,tests/unit")
         -ie <str>  Extensions to include, excluding others (e.g. "go,sh,zig")
         -if <str>  Files to include, excluding others (name or path, e.g. "main.lua,src/index.ts")
         -il <str>  Languages to include, excluding others (e.g. "Python,JavaScript,C++")
+        -ll        Print logical lines of code (statements) alongside loc
         -ml <int>  Maximum number of languages to print per directory (default: 1,000)
+        -mx <str>  How to count lines with code and comments ["code", "comment", "both"] (default: "code")
         -p         Print loc as a percentage of overall total
         -q         Suppress non-critical error messages
-        -s  <str>  How to sort results ["loc", "size", "files"] (default: "loc")
+        -s  <str>  How to sort results (default: "loc")
+                   ["loc", "lloc", "uloc", "testloc", "prodloc", "testratio", "generated", "comments",
+                    "docs", "mixed", "blanks", "complexity", "markers", "maxlen", "meanlen", "longlines",
+                    "size", "files"]
         -sd <int>  Maximum depth of subdirectories to search (default: 1,000)
+        -t         Print the number of markers (e.g. "TODO") in comments
+            -tl        List each marker as file:line after the results
+            -tm <str>  Markers to count (default: "TODO,FIXME,HACK,XXX")
+        --tests    Print test and production loc, and the ratio of test loc to production loc
+            -tp <str>  Test file name patterns, or directory name patterns ending in "/" (e.g. "*_it.go,e2e/")
+        -u         Print unique lines of code (distinct non-blank lines, including comments)
+        -w  <int>  Print line length statistics, with longlines counting lines over <int> characters
         --help     Print this message and exit
         --license  Print license information and exit
         --version  Print version and exit`
@@ -193,9 +269,52 @@ func processFlags() {
 		excludeLangs = strings.Split(*excludeLangsFlag, ",")
 	}
 
-	if !slices.Contains([]string{"loc", "size", "files"}, *sortColumn) {
+	// skip empty markers, which would match everywhere
+	for _, marker := range strings.Split(*markersFlag, ",") {
+		if marker != "" {
+			markers = append(markers, marker)
+		}
+	}
+
+	if *testPatternsFlag != "" {
+		for _, pattern := range strings.Split(*testPatternsFlag, ",") {
+			if dirPattern, isDir := strings.CutSuffix(pattern, "/"); isDir {
+				userTestDirs = append(userTestDirs, dirPattern)
+			} else {
+				userTestPatterns = append(userTestPatterns, pattern)
+			}
+		}
+	}
+
+	if *lineWidth < 0 {
+		fmt.Printf("-w input %d is invalid, line length statistics will not be printed\n", *lineWidth)
+		*lineWidth = 0
+	}
+
+	if c := findColumn(*sortColumn); c == nil {
 		fmt.Printf("-s input \"%s\" is invalid, defaulting to \"loc\"\n", *sortColumn)
-		// "loc" is already the default option when sorting results
+		*sortColumn = "loc"
+	} else if !c.isShown() {
+		fmt.Printf("-s input \"%s\" is not printed without %s, defaulting to \"loc\"\n", *sortColumn, c.shownFlag)
+		*sortColumn = "loc"
+	}
+
+	if _, ok := cocomoModels[*cocomoType]; !ok {
+		fmt.Printf("-ct input \"%s\" is invalid, defaulting to \"organic\"\n", *cocomoType)
+		*cocomoType = "organic"
+	}
+	if *cocomoSalary < 0 {
+		fmt.Printf("-cs input %d is invalid, defaulting to 56,286\n", *cocomoSalary)
+		*cocomoSalary = 56_286
+	}
+	if *cocomoOverhead < 0 {
+		fmt.Printf("-co input %g is invalid, defaulting to 2.4\n", *cocomoOverhead)
+		*cocomoOverhead = 2.4
+	}
+
+	if !slices.Contains([]string{"code", "comment", "both"}, *countMixedAs) {
+		fmt.Printf("-mx input \"%s\" is invalid, defaulting to \"code\"\n", *countMixedAs)
+		*countMixedAs = "code"
 	}
 
 	if *maxFileReaders < 1 {
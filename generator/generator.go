@@ -21,6 +21,12 @@ var languagesInfo map[string]map[string]any
 // customMappings is the map version of the user's custom_mappings.json.
 var customMappings map[string]map[string]any
 
+// nestingPairs lists the multi-line comment pairs that can be nested for languages where only some of them can,
+// since languages.json only records whether a language's multi-line comments can be nested.
+var nestingPairs = map[string][]any{
+	"D": {[]any{"/+", "+/"}},
+}
+
 // main generates languages.go from scc's languages.json and user's custom_mappings.json.
 func main() {
 	loadFiles()
@@ -90,20 +96,42 @@ func generateText() []string {
 	// fileLines contains the lines of languages.go.
 	fileLines := []string{"package main\n", doNotEditText}
 
-	// langsUsed contains the languages which appear as values in the extensions and fileNames maps.
+	// langsUsed contains the languages which appear as values in the extensions, fileNames, and shebangs maps.
 	langsUsed := make(map[string]struct{})
-	extensionMappings, fileNameMappings, singleCharMappings := gatherLanguageInfo()
-	fileLines, langsUsed = generateExtensionsMap(fileLines, langsUsed, extensionMappings)
-	fileLines, langsUsed = generateFileNamesMap(fileLines, langsUsed, fileNameMappings)
-	fileLines = generateSingleCharsMap(fileLines, langsUsed, singleCharMappings)
+	info := gatherLanguageInfo()
+	fileLines, langsUsed = generateExtensionsMap(fileLines, langsUsed, info.extensionMappings)
+	fileLines, langsUsed = generateFileNamesMap(fileLines, langsUsed, info.fileNameMappings)
+	fileLines, langsUsed = generateShebangsMap(fileLines, langsUsed, info.shebangMappings)
+	fileLines = generateSingleCharsMap(fileLines, langsUsed, info.singleCharMappings)
+	fileLines = generateMultiCharsMap(fileLines, langsUsed, info.multiCharMappings)
+	fileLines = generateNestedMap(fileLines, langsUsed, info.nestedMappings)
+	fileLines = generateQuotesMap(fileLines, langsUsed, info.quoteMappings)
+	fileLines = generateComplexityMap(fileLines, langsUsed, info.complexityMappings)
 	return fileLines
 }
 
+// languageInfo contains the relevant information from languages.json, organized into maps.
+type languageInfo struct {
+	extensionMappings  map[string]string
+	fileNameMappings   map[string]string
+	shebangMappings    map[string]string
+	singleCharMappings map[string][]any
+	multiCharMappings  map[string][]any
+	nestedMappings     map[string][]any
+	quoteMappings      map[string][]any
+	complexityMappings map[string][]any
+}
+
 // gatherLanguageInfo loads relevant information from languages.json into maps.
-func gatherLanguageInfo() (map[string]string, map[string]string, map[string][]any) {
+func gatherLanguageInfo() languageInfo {
 	extensionMappings := make(map[string]string)
 	fileNameMappings := make(map[string]string)
+	shebangMappings := make(map[string]string)
 	singleCharMappings := make(map[string][]any)
+	multiCharMappings := make(map[string][]any)
+	nestedMappings := make(map[string][]any)
+	quoteMappings := make(map[string][]any)
+	complexityMappings := make(map[string][]any)
 
 	for language, info := range languagesInfo {
 		// process extensions, resolve conflicts
@@ -116,20 +144,8 @@ func gatherLanguageInfo() (map[string]string, map[string]string, map[string][]an
 				if !ok {
 					fmt.Println("Error reading extensions for", language)
 				} else {
-					// skip extensions which contain periods; loc doesn't recognize multiple extensions
-					if strings.Count(ext, ".") > 0 {
-						continue
-					}
-					currentMapping, ok := extensionMappings[ext]
-					if ok {
-						// sort languages alphabetically for deterministic output
-						langs := strings.Split(currentMapping, " or ")
-						langs = append(langs, language)
-						sort.Strings(langs)
-						extensionMappings[ext] = strings.Join(langs, " or ")
-					} else {
-						extensionMappings[ext] = language
-					}
+					// extensions can contain periods (e.g. "d.ts"), in which case the longest match wins
+					addMapping(extensionMappings, ext, language)
 				}
 			}
 		}
@@ -148,6 +164,20 @@ func gatherLanguageInfo() (map[string]string, map[string]string, map[string][]an
 			}
 		}
 
+		// process shebang interpreters
+		shebangs, ok := info["shebangs"].([]any)
+		// no warning because shebangs are optional in languages.json
+		if ok {
+			for _, interpreter := range shebangs {
+				interpreter, ok := interpreter.(string)
+				if !ok {
+					fmt.Println("Error reading shebangs for", language)
+				} else {
+					addMapping(shebangMappings, interpreter, language)
+				}
+			}
+		}
+
 		// process single line comment characters
 		chars, ok := info["line_comment"].([]any)
 		if !ok {
@@ -155,9 +185,65 @@ func gatherLanguageInfo() (map[string]string, map[string]string, map[string][]an
 		} else {
 			singleCharMappings[language] = chars
 		}
+
+		// process multi-line comment characters
+		pairs, ok := info["multi_line"].([]any)
+		if !ok {
+			fmt.Println("Error getting multi-line comment chars for", language)
+		} else {
+			multiCharMappings[language] = pairs
+		}
+
+		// process which multi-line comments can be nested
+		nested, ok := info["nestedmultiline"].(bool)
+		// no warning because nestedmultiline is optional in languages.json
+		if ok && nested {
+			if pairs, ok := nestingPairs[language]; ok {
+				nestedMappings[language] = pairs
+			} else {
+				nestedMappings[language] = multiCharMappings[language]
+			}
+		}
+
+		// process string literal quotes
+		quotes, ok := info["quotes"].([]any)
+		// no warning because quotes are optional in languages.json
+		if ok {
+			quoteMappings[language] = quotes
+		}
+
+		// process complexity check tokens
+		checks, ok := info["complexitychecks"].([]any)
+		// no warning because complexity checks are optional in languages.json
+		if ok {
+			complexityMappings[language] = checks
+		}
+	}
+
+	return languageInfo{
+		extensionMappings:  extensionMappings,
+		fileNameMappings:   fileNameMappings,
+		shebangMappings:    shebangMappings,
+		singleCharMappings: singleCharMappings,
+		multiCharMappings:  multiCharMappings,
+		nestedMappings:     nestedMappings,
+		quoteMappings:      quoteMappings,
+		complexityMappings: complexityMappings,
 	}
+}
 
-	return extensionMappings, fileNameMappings, singleCharMappings
+// addMapping maps key to language, resolving conflicts with existing mappings by joining the languages with " or ".
+func addMapping(mappings map[string]string, key, language string) {
+	currentMapping, ok := mappings[key]
+	if ok {
+		// sort languages alphabetically for deterministic output
+		langs := strings.Split(currentMapping, " or ")
+		langs = append(langs, language)
+		sort.Strings(langs)
+		mappings[key] = strings.Join(langs, " or ")
+	} else {
+		mappings[key] = language
+	}
 }
 
 // generateExtensionsMap generates the definition for the extensions map.
@@ -188,7 +274,10 @@ func generateExtensionsMap(
 		if language != "" {
 			line := fmt.Sprintf("\n\t\"%s\": \"%s\",", extension, language)
 			fileLines = append(fileLines, line)
-			langsUsed[language] = struct{}{}
+			// record each language in conflicts, since loc may resolve them using file contents
+			for _, lang := range strings.Split(language, " or ") {
+				langsUsed[lang] = struct{}{}
+			}
 		}
 	}
 	fileLines = append(fileLines, "\n}\n")
@@ -230,6 +319,44 @@ func generateFileNamesMap(
 	return fileLines, langsUsed
 }
 
+// generateShebangsMap generates the definition for the shebangs map.
+func generateShebangsMap(
+	fileLines []string,
+	langsUsed map[string]struct{},
+	shebangMappings map[string]string,
+) ([]string, map[string]struct{}) {
+	// create union of shebangMappings and customMappings, record languages referenced
+	customShebangs, ok := customMappings["shebangs"]
+	if ok {
+		for interpreter, language := range customShebangs {
+			language, ok := language.(string)
+			if !ok {
+				fmt.Println("Error reading custom shebangs for", language)
+			} else {
+				shebangMappings[interpreter] = language
+			}
+		}
+	}
+
+	// record file lines
+	fileLines = append(fileLines, "\n// shebangs is the map of interpreters in shebang lines recognized as a particular language.")
+	fileLines = append(fileLines, "\nvar shebangs = map[string]string{")
+	for _, interpreter := range sortKeys(shebangMappings) {
+		language := shebangMappings[interpreter]
+		// skip custom mappings to blank strings
+		if language != "" {
+			line := fmt.Sprintf("\n\t\"%s\": \"%s\",", interpreter, language)
+			fileLines = append(fileLines, line)
+			// record each language in conflicts, since loc may resolve them using file contents
+			for _, lang := range strings.Split(language, " or ") {
+				langsUsed[lang] = struct{}{}
+			}
+		}
+	}
+	fileLines = append(fileLines, "\n}\n")
+	return fileLines, langsUsed
+}
+
 // generateSingleCharsMap generates the definition for the singleLineCommentChars map.
 func generateSingleCharsMap(
 	fileLines []string,
@@ -284,6 +411,213 @@ func generateSingleCharsMap(
 	return fileLines
 }
 
+// generateMultiCharsMap generates the definition for the multiLineCommentChars map.
+func generateMultiCharsMap(
+	fileLines []string,
+	langsUsed map[string]struct{},
+	multiCharMappings map[string][]any,
+) []string {
+	// create union of multiCharMappings and customMultiChars
+	customMultiChars, ok := customMappings["multiLineCommentChars"]
+	if ok {
+		for language, pairs := range customMultiChars {
+			pairs, ok := pairs.([]any)
+			if !ok {
+				fmt.Println("Error getting custom multi-line comment chars for", language)
+			} else {
+				multiCharMappings[language] = pairs
+			}
+		}
+	}
+
+	// record file lines
+	fileLines = append(fileLines, "\n// multiLineCommentChars is the map of multi-line comment start and end characters for all languages.")
+	fileLines = append(fileLines, "\nvar multiLineCommentChars = map[string][][2]string{")
+	for _, language := range sortKeys(multiCharMappings) {
+		// skip languages that don't appear in either of the other maps
+		if _, ok := langsUsed[language]; !ok {
+			continue
+		}
+
+		pairs := formatCommentPairs(language, multiCharMappings[language])
+		// languages without multi-line comments are left out of the map
+		if len(pairs) > 0 {
+			line := fmt.Sprintf("\n\t\"%s\": {%s},", language, strings.Join(pairs, ", "))
+			fileLines = append(fileLines, line)
+		}
+	}
+	fileLines = append(fileLines, "\n}\n")
+	return fileLines
+}
+
+// formatCommentPairs formats a list of multi-line comment start and end characters as Go [2]string literals.
+func formatCommentPairs(language string, commentPairs []any) []string {
+	var pairs []string
+	for _, pair := range commentPairs {
+		// each pair is a list containing the start and end characters
+		chars, ok := pair.([]any)
+		if !ok || len(chars) != 2 {
+			fmt.Println("Error reading multi-line comment chars for", language)
+			continue
+		}
+		start, startOk := chars[0].(string)
+		end, endOk := chars[1].(string)
+		if !startOk || !endOk {
+			fmt.Println("Error reading multi-line comment chars for", language)
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("{%q, %q}", start, end))
+	}
+	return pairs
+}
+
+// generateNestedMap generates the definition for the nestedMultiLineComments map.
+func generateNestedMap(
+	fileLines []string,
+	langsUsed map[string]struct{},
+	nestedMappings map[string][]any,
+) []string {
+	// create union of nestedMappings and customNested
+	customNested, ok := customMappings["nestedMultiLineComments"]
+	if ok {
+		for language, pairs := range customNested {
+			pairs, ok := pairs.([]any)
+			if !ok {
+				fmt.Println("Error reading custom nested multi-line comments for", language)
+			} else {
+				nestedMappings[language] = pairs
+			}
+		}
+	}
+
+	// record file lines
+	fileLines = append(fileLines, "\n// nestedMultiLineComments is the map of multi-line comment pairs that can be nested for all languages.")
+	fileLines = append(fileLines, "\nvar nestedMultiLineComments = map[string][][2]string{")
+	for _, language := range sortKeys(nestedMappings) {
+		// skip languages that don't appear in either of the other maps
+		if _, ok := langsUsed[language]; !ok {
+			continue
+		}
+
+		pairs := formatCommentPairs(language, nestedMappings[language])
+		// custom mappings to an empty list remove languages from the map
+		if len(pairs) > 0 {
+			line := fmt.Sprintf("\n\t\"%s\": {%s},", language, strings.Join(pairs, ", "))
+			fileLines = append(fileLines, line)
+		}
+	}
+	fileLines = append(fileLines, "\n}\n")
+	return fileLines
+}
+
+// generateQuotesMap generates the definition for the quoteChars map.
+func generateQuotesMap(
+	fileLines []string,
+	langsUsed map[string]struct{},
+	quoteMappings map[string][]any,
+) []string {
+	// create union of quoteMappings and customQuotes
+	customQuotes, ok := customMappings["quoteChars"]
+	if ok {
+		for language, quotes := range customQuotes {
+			quotes, ok := quotes.([]any)
+			if !ok {
+				fmt.Println("Error getting custom quotes for", language)
+			} else {
+				quoteMappings[language] = quotes
+			}
+		}
+	}
+
+	// record file lines
+	fileLines = append(fileLines, "\n// quoteChars is the map of string literal quotes for all languages.")
+	fileLines = append(fileLines, "\nvar quoteChars = map[string][]quote{")
+	for _, language := range sortKeys(quoteMappings) {
+		// skip languages that don't appear in either of the other maps
+		if _, ok := langsUsed[language]; !ok {
+			continue
+		}
+
+		var quotes []string
+		for _, q := range quoteMappings[language] {
+			// each quote is an object with start and end characters and optional settings
+			q, ok := q.(map[string]any)
+			if !ok {
+				fmt.Println("Error reading quotes for", language)
+				continue
+			}
+			start, startOk := q["start"].(string)
+			end, endOk := q["end"].(string)
+			if !startOk || !endOk {
+				fmt.Println("Error reading quotes for", language)
+				continue
+			}
+			fields := []string{fmt.Sprintf("start: %q", start), fmt.Sprintf("end: %q", end)}
+			if ignoreEscape, _ := q["ignoreEscape"].(bool); ignoreEscape {
+				fields = append(fields, "ignoreEscape: true")
+			}
+			if docString, _ := q["docString"].(bool); docString {
+				fields = append(fields, "docString: true")
+			}
+			quotes = append(quotes, fmt.Sprintf("{%s}", strings.Join(fields, ", ")))
+		}
+		// languages without quotes are left out of the map
+		if len(quotes) > 0 {
+			line := fmt.Sprintf("\n\t\"%s\": {%s},", language, strings.Join(quotes, ", "))
+			fileLines = append(fileLines, line)
+		}
+	}
+	fileLines = append(fileLines, "\n}\n")
+	return fileLines
+}
+
+// generateComplexityMap generates the definition for the complexityChecks map.
+func generateComplexityMap(
+	fileLines []string,
+	langsUsed map[string]struct{},
+	complexityMappings map[string][]any,
+) []string {
+	// create union of complexityMappings and customChecks
+	customChecks, ok := customMappings["complexityChecks"]
+	if ok {
+		for language, checks := range customChecks {
+			checks, ok := checks.([]any)
+			if !ok {
+				fmt.Println("Error getting custom complexity checks for", language)
+			} else {
+				complexityMappings[language] = checks
+			}
+		}
+	}
+
+	// record file lines
+	fileLines = append(fileLines, "\n// complexityChecks is the map of tokens which add to the complexity of code in each language.")
+	fileLines = append(fileLines, "\nvar complexityChecks = map[string][]string{")
+	for _, language := range sortKeys(complexityMappings) {
+		// skip languages that don't appear in either of the other maps
+		if _, ok := langsUsed[language]; !ok {
+			continue
+		}
+
+		var checks []string
+		for _, check := range complexityMappings[language] {
+			check, ok := check.(string)
+			if !ok {
+				fmt.Println("Error reading complexity checks for", language)
+				continue
+			}
+			checks = append(checks, fmt.Sprintf("%q", check))
+		}
+		// languages without complexity checks are left out of the map
+		if len(checks) > 0 {
+			line := fmt.Sprintf("\n\t\"%s\": {%s},", language, strings.Join(checks, ", "))
+			fileLines = append(fileLines, line)
+		}
+	}
+	fileLines = append(fileLines, "\n}\n")
+	return fileLines
+}
+
 // sortKeys creates a sorted slice of a map's string keys.
 func sortKeys[k any](sourceMap map[string]k) []string {
 	var sortedKeys []string
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// headSize is the number of bytes at the start of a file which are read to determine its language.
+	headSize = 16_000
+	// tailSize is the number of bytes at the end of a file which are checked for modelines.
+	tailSize = 4_000
+	// modelineLines is the number of lines at the start and end of a file which are checked for Vim modelines.
+	modelineLines = 5
+)
+
+var (
+	// vimModeline matches Vim modelines, capturing their options.
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:\s*(?:set?\s+)?(.*)`)
+	// vimFileType matches the file type option in the options of a Vim modeline, capturing its value.
+	vimFileType = regexp.MustCompile(`(?:^|[\s:])(?:ft|filetype|syn|syntax)=([\w+#-]+)`)
+	// emacsModeline matches Emacs modelines, capturing their variables.
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	// emacsMode matches the mode variable in the variables of an Emacs modeline, capturing its value.
+	emacsMode = regexp.MustCompile(`(?i)(?:^|;)\s*mode\s*:\s*([\w+#-]+)`)
+)
+
+// binaryExtensions contains the extensions of common binary formats, which aren't read to determine their language.
+var binaryExtensions = map[string]bool{
+	"7z": true, "a": true, "avi": true, "bin": true, "bmp": true, "class": true, "dll": true, "dylib": true,
+	"eot": true, "exe": true, "gif": true, "gz": true, "ico": true, "jar": true, "jpeg": true, "jpg": true,
+	"mov": true, "mp3": true, "mp4": true, "o": true, "otf": true, "pdf": true, "png": true, "pyc": true,
+	"so": true, "tar": true, "tgz": true, "ttf": true, "wasm": true, "wav": true, "webm": true, "webp": true,
+	"woff": true, "woff2": true, "xz": true, "zip": true, "zst": true,
+}
+
+// languageAliases maps names for languages which don't match a language's name, extension, or interpreter.
+var languageAliases = map[string]string{
+	"c++":             "C++",
+	"cs":              "C#",
+	"dosbatch":        "Batch",
+	"elisp":           "Emacs Lisp",
+	"emacs-lisp":      "Emacs Lisp",
+	"javascriptreact": "JavaScript",
+	"js2":             "JavaScript",
+	"make":            "Makefile",
+	"objc":            "Objective-C",
+	"ps1":             "Powershell",
+	"shell-script":    "Shell",
+	"tex":             "TeX",
+	"typescriptreact": "TypeScript",
+	"vim":             "Vim Script",
+}
+
+/*
+heuristics maps languages to patterns which are characteristic of their code, based on GitHub
+linguist's heuristics. These are used to choose between languages which share an extension.
+*/
+var heuristics = map[string][]*regexp.Regexp{
+	"C Header": {
+		regexp.MustCompile(`(?m)^\s*#\s*include\s*<(stdio|stdlib|string|stdint|stdbool|stddef|unistd)\.h>`),
+		regexp.MustCompile(`(?m)^\s*typedef\s+(struct|enum|union)\b`),
+	},
+	"C++ Header": {
+		regexp.MustCompile(`(?m)^\s*#\s*include\s*<(iostream|string|vector|map|memory|algorithm|cstdint|cstdlib)>`),
+		regexp.MustCompile(`(?m)^\s*(namespace\s+\w+|template\s*<|class\s+\w+\s*[:{]|(public|private|protected)\s*:)`),
+		regexp.MustCompile(`\bstd::`),
+	},
+	"Coq": {
+		regexp.MustCompile(`(?m)^\s*(Theorem|Lemma|Proof|Qed|Definition|Inductive|Fixpoint|Require\s+Import)\b`),
+	},
+	"F#": {
+		regexp.MustCompile(`(?m)^\s*(let|open|module|type)\s+[\w.]+`),
+		regexp.MustCompile(`(?m)^\s*\|\s*\w+\s*->`),
+	},
+	"Forth": {
+		regexp.MustCompile(`(?m)^\s*:\s+\S+.*;\s*$`),
+		regexp.MustCompile(`(?i)\b(dup|swap|drop|over)\b`),
+	},
+	"GLSL": {
+		regexp.MustCompile(`(?m)^\s*#version\s+\d+`),
+		regexp.MustCompile(`\b(uniform|varying|gl_Position|gl_FragColor|vec[234])\b`),
+	},
+	"MATLAB": {
+		regexp.MustCompile(`(?m)^\s*function\s+(\[[^\]]*\]|\w+)\s*=`),
+		regexp.MustCompile(`(?m)^\s*%`),
+		regexp.MustCompile(`\b(disp|zeros|ones|plot|fprintf|numel)\s*\(`),
+	},
+	"Objective-C": {
+		regexp.MustCompile(`(?m)^\s*@(interface|implementation|protocol|property|synthesize|end)\b`),
+		regexp.MustCompile(`(?m)^\s*#import\s+[<"]`),
+		regexp.MustCompile(`\bNS[A-Z]\w+`),
+	},
+	"Perl": {
+		regexp.MustCompile(`(?m)^\s*use\s+(strict|warnings|[A-Z]\w*(::\w+)*)\b`),
+		regexp.MustCompile(`\bmy\s+[$@%]`),
+		regexp.MustCompile(`(?m)^\s*sub\s+\w+`),
+		regexp.MustCompile(`(?m)^#!.*\bperl\b`),
+	},
+	"Prolog": {
+		regexp.MustCompile(`(?m)^[a-z]\w*(\(.*\))?\s*:-`),
+		regexp.MustCompile(`(?m)^\s*:-\s*(module|use_module|dynamic|initialization)\b`),
+	},
+	"Verilog": {
+		regexp.MustCompile(`(?m)^\s*module\s+\w+\s*[(;#]`),
+		regexp.MustCompile(`(?m)^\s*(always|assign|wire|reg|endmodule)\b`),
+	},
+	"V": {
+		regexp.MustCompile(`(?m)^\s*(pub\s+)?fn\s+\w+\s*\(`),
+		regexp.MustCompile(`(?m)^\s*module\s+\w+\s*$`),
+		regexp.MustCompile(`:=`),
+	},
+}
+
+/*
+detectLanguage determines the language of a file by a modeline in its contents, then by its name,
+then by its longest recognized extension, then by the interpreter in its shebang line. ok is false if the
+file is not recognized as code. If the file's name or extension belongs to multiple languages, its
+contents are used to choose one. data contains the file's contents if they were read in full, so that
+small files only need to be read once.
+*/
+func detectLanguage(fullPath, fileName string) (lang string, ok bool, data []byte) {
+	lang, ok = fileNames[fileName]
+	for _, ext := range fileExtensions(fileName) {
+		if ok {
+			break
+		}
+		lang, ok = extensions[ext]
+	}
+
+	// binary files (e.g. images) can't have modelines or shebang lines, so they aren't read
+	if !ok && hasBinaryExtension(fileName) {
+		return "", false, nil
+	}
+
+	head, tail, complete, readOk := readHeadAndTail(fullPath)
+	if !readOk {
+		return lang, ok, nil
+	}
+	if complete {
+		data = head
+	}
+	if modelineLang, modelineOk := modelineLanguage(head, tail); modelineOk {
+		return modelineLang, true, data
+	}
+	if !ok {
+		lang, ok = shebangLanguage(head)
+	}
+	if ok && strings.Contains(lang, " or ") {
+		lang = disambiguate(head, lang)
+	}
+	return lang, ok, data
+}
+
+// hasBinaryExtension reports whether a file name has the extension of a common binary format.
+func hasBinaryExtension(fileName string) bool {
+	exts := fileExtensions(fileName)
+	return len(exts) > 0 && binaryExtensions[strings.ToLower(exts[len(exts)-1])]
+}
+
+/*
+fileExtensions returns the possible extensions of a file name from longest to shortest,
+e.g. "d.ts" and "ts" for "index.d.ts".
+*/
+func fileExtensions(fileName string) []string {
+	var exts []string
+	for i, char := range fileName {
+		if char == '.' {
+			exts = append(exts, fileName[i+1:])
+		}
+	}
+	return exts
+}
+
+/*
+fileExtension returns the extension of a file name used by -ie and -ee, which is the longest of
+its possible extensions which is recognized or used by -ie or -ee, or otherwise the shortest.
+*/
+func fileExtension(fileName string) string {
+	exts := fileExtensions(fileName)
+	if len(exts) == 0 {
+		return ""
+	}
+	for _, ext := range exts {
+		_, ok := extensions[ext]
+		if ok || slices.Contains(includeExts, ext) || slices.Contains(excludeExts, ext) {
+			return ext
+		}
+	}
+	return exts[len(exts)-1]
+}
+
+/*
+disambiguate chooses between the languages in a label like "C Header or C++ Header" by the number of
+their heuristics which match the start of a file. The label is returned if there is no single best match.
+*/
+func disambiguate(head []byte, label string) string {
+	var bestLang string
+	var bestScore, bestCount int
+	for _, lang := range strings.Split(label, " or ") {
+		var score int
+		for _, pattern := range heuristics[lang] {
+			if pattern.Match(head) {
+				score++
+			}
+		}
+		if score > bestScore {
+			bestLang, bestScore, bestCount = lang, score, 1
+		} else if score == bestScore {
+			bestCount++
+		}
+	}
+
+	if bestScore == 0 || bestCount > 1 {
+		return label
+	}
+	return bestLang
+}
+
+/*
+readHeadAndTail reads the start of a file, and its end if it doesn't fit in the start. complete is true
+if the whole file fits in the start, and ok is false if the file can't be read.
+*/
+func readHeadAndTail(fullPath string) (head, tail []byte, complete, ok bool) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		// unreadable code files are reported when they're counted, and other files don't matter
+		return nil, nil, false, false
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			warn("Error closing file:", err)
+		}
+	}(file)
+
+	head = make([]byte, headSize)
+	n, err := io.ReadFull(file, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// the whole file fits in head
+		return head[:n], nil, true, true
+	} else if err != nil {
+		warn("Error reading file:", err)
+		return nil, nil, false, false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		warn("Error checking file:", err)
+		return head, nil, false, true
+	}
+	// don't re-read any of head
+	tailStart := max(int64(headSize), info.Size()-tailSize)
+	tail = make([]byte, info.Size()-tailStart)
+	n, err = file.ReadAt(tail, tailStart)
+	if err != nil && err != io.EOF {
+		warn("Error reading file:", err)
+		return head, nil, false, true
+	}
+	return head, tail[:n], false, true
+}
+
+// shebangLanguage determines the language of a file by the interpreter in its shebang line, if it has one.
+func shebangLanguage(head []byte) (string, bool) {
+	line, _, _ := strings.Cut(string(head), "\n")
+	interpreter, ok := parseShebang(line)
+	if !ok {
+		return "", false
+	}
+	if lang, ok := shebangs[interpreter]; ok {
+		return lang, true
+	}
+	// remove version numbers, e.g. "python3.12" to "python"
+	lang, ok := shebangs[strings.TrimRight(interpreter, "0123456789.")]
+	return lang, ok
+}
+
+/*
+modelineLanguage determines the language of a file by a Vim modeline (e.g. "vim: set ft=python:")
+in its first or last few lines, or an Emacs modeline (e.g. "-*- mode: ruby -*-") in its first two.
+*/
+func modelineLanguage(head, tail []byte) (string, bool) {
+	lines := strings.Split(string(head), "\n")
+	if len(tail) > 0 {
+		// the first line of tail may be partial, which doesn't matter if there are enough lines
+		lines = append(lines, strings.Split(string(tail), "\n")...)
+	}
+
+	// check the first lines before the last lines
+	var candidates []string
+	for i, line := range lines {
+		if i < modelineLines || i >= len(lines)-modelineLines {
+			candidates = append(candidates, line)
+		}
+	}
+
+	for i, line := range candidates {
+		// skip the regular expressions for lines which can't contain a modeline
+		if !strings.Contains(line, "-*-") && (!strings.Contains(line, ":") ||
+			!strings.Contains(line, "vi") && !strings.Contains(line, "ex")) {
+			continue
+		}
+
+		var name string
+		if match := vimModeline.FindStringSubmatch(line); match != nil {
+			if option := vimFileType.FindStringSubmatch(match[1]); option != nil {
+				name = option[1]
+			}
+		} else if match := emacsModeline.FindStringSubmatch(line); match != nil && i < 2 {
+			name = strings.TrimSpace(match[1])
+			// the mode can be the only variable, or one of several (e.g. "-*- mode: ruby; coding: utf-8 -*-")
+			if option := emacsMode.FindStringSubmatch(name); option != nil {
+				name = option[1]
+			} else if strings.Contains(name, ":") {
+				continue
+			}
+			name = strings.TrimSuffix(name, "-mode")
+		}
+
+		if name == "" {
+			continue
+		}
+		if lang, ok := languageFromName(name); ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+/*
+languageFromName converts a name used for a language in a file, such as a Vim file type, an Emacs mode,
+or a Markdown info string, into one of loc's languages. Names can be aliases of languages, languages
+themselves, extensions, or interpreters (e.g. "sh" or "python3").
+*/
+func languageFromName(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if alias, ok := languageAliases[name]; ok {
+		name = strings.ToLower(alias)
+	}
+	if lang, ok := languageNames()[name]; ok {
+		return lang, true
+	}
+	if lang, ok := extensions[name]; ok && !strings.Contains(lang, " or ") {
+		return lang, true
+	}
+	if lang, ok := shebangs[name]; ok && !strings.Contains(lang, " or ") {
+		return lang, true
+	}
+	return "", false
+}
+
+// languageNames returns a map of lowercase language names to the languages recognized by loc.
+var languageNames = sync.OnceValue(func() map[string]string {
+	names := make(map[string]string)
+	for _, mappings := range []map[string]string{extensions, fileNames, shebangs} {
+		for _, label := range mappings {
+			for _, lang := range strings.Split(label, " or ") {
+				names[strings.ToLower(lang)] = lang
+			}
+		}
+	}
+	return names
+})
+
+/*
+parseShebang returns the name of the interpreter in a shebang line (e.g. "python3" in
+"#!/usr/bin/python3" or "#!/usr/bin/env -S python3 -u"). ok is false if line isn't a shebang line.
+*/
+func parseShebang(line string) (interpreter string, ok bool) {
+	line, ok = strings.CutPrefix(line, "#!")
+	if !ok {
+		return "", false
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+	// shebang paths use forward slashes on every OS
+	interpreter = path.Base(filepath.ToSlash(fields[0]))
+	if interpreter != "env" {
+		return interpreter, true
+	}
+
+	// the interpreter is the first argument to env which isn't an option or variable assignment
+	for _, arg := range fields[1:] {
+		if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+			return path.Base(arg), true
+		}
+	}
+	return "", false
+}
@@ -14,7 +14,6 @@ const version = "v3.3.1-alpha"
 
 // cwd is the current working directory.
 var cwd string
-var totalLoc, totalBytes, totalFiles float64
 
 // main is loc's entry point.
 func main() {
@@ -45,6 +44,8 @@ func main() {
 		}
 	}
 
+	searchRoots = dirPaths
+
 	// mainDir is the "root" directory from which files and subdirectories are indexed.
 	var mainDir *directory
 	if len(dirPaths) == 1 {
@@ -56,10 +57,8 @@ func main() {
 		// create a fake directory to show totals across multiple directory args
 		mainDir = &directory{
 			printSubdirs: 1 <= *maxPrintDepth,
-			locCounts:    make(map[string]int),
-			fileCounts:   make(map[string]int),
-			byteCounts:   make(map[string]int),
 		}
+		mainDir.initCounts()
 
 		for _, path := range dirPaths {
 			subdir, ok := newDirectory(path, 1, len(includeDirs) == 0)
@@ -71,16 +70,29 @@ func main() {
 		mainDir.countDirLoc()
 	}
 
-	if len(mainDir.fileCounts) == 0 {
+	// warn about skipped files after the results have been printed
+	defer warnBinaryFiles()
+	if *dedupFlag {
+		defer printDuplicates()
+	}
+
+	if len(mainDir.locCounts) == 0 {
 		fmt.Println("No code files found")
 		return
 	}
 
 	if *percentagesFlag {
-		totalLoc = float64(sumMapValues(mainDir.locCounts))
-		totalBytes = float64(sumMapValues(mainDir.byteCounts))
-		totalFiles = float64(sumMapValues(mainDir.fileCounts))
+		for _, c := range columns {
+			c.total = float64(c.dirTotalCount(mainDir))
+		}
 	}
 
 	mainDir.printTreeLoc()
+
+	if *cocomoFlag {
+		printCocomo(mainDir)
+	}
+	if *listMarkersFlag {
+		printMarkers(mainDir)
+	}
 }
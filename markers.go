@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// markerLine is an occurrence of a marker (e.g. "TODO") in a file's comments.
+type markerLine struct {
+	marker string
+	// line is the number of the line in the file, starting from 1.
+	line int
+	// text is the comment from the marker to the end of the line.
+	text string
+}
+
+// countMarkers counts the markers in a line's comment text, if -t or -tl is used.
+func (f *file) countMarkers(comment string, lineNum int) {
+	if !*printMarkersFlag && !*listMarkersFlag {
+		return
+	}
+	for _, marker := range markers {
+		for i := 0; i < len(comment); {
+			index := strings.Index(comment[i:], marker)
+			if index == -1 {
+				break
+			}
+			start, end := i+index, i+index+len(marker)
+			// always advance, even if the marker is somehow empty
+			i = max(end, start+1)
+
+			// markers must be whole words, so "TODO" doesn't match "TODOS"
+			if (start > 0 && isWordChar(comment[start-1])) || (end < len(comment) && isWordChar(comment[end])) {
+				continue
+			}
+			f.markers++
+			if *listMarkersFlag {
+				f.markerLines = append(f.markerLines, markerLine{
+					marker: marker,
+					line:   lineNum,
+					text:   strings.TrimSpace(comment[start:]),
+				})
+			}
+		}
+	}
+}
+
+// printMarkers prints each occurrence of a marker in the files that descend from d, as file:line.
+func printMarkers(d *directory) {
+	// occurrences contains the files' paths relative to cwd where possible, with their marker lines.
+	type occurrence struct {
+		path string
+		markerLine
+	}
+	var occurrences []occurrence
+	totals := make(map[string]int)
+	for _, f := range d.appendAllFiles(nil) {
+		path := relToCwd(f.fullPath)
+		var lines []markerLine
+		if !languageFiltered(f.language) {
+			lines = f.markerLines
+		}
+		for _, embedded := range f.embedded {
+			if !languageFiltered(embedded.language) {
+				lines = append(lines, embedded.markerLines...)
+			}
+		}
+		for _, line := range lines {
+			occurrences = append(occurrences, occurrence{path, line})
+			totals[line.marker]++
+		}
+	}
+	if len(occurrences) == 0 {
+		return
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		if occurrences[i].path != occurrences[j].path {
+			return occurrences[i].path < occurrences[j].path
+		}
+		return occurrences[i].line < occurrences[j].line
+	})
+
+	// summarize the totals in the order the markers were given
+	var summary []string
+	for _, marker := range markers {
+		if totals[marker] > 0 {
+			summary = append(summary, fmt.Sprintf("%s: %s", marker, addCommas(totals[marker])))
+		}
+	}
+	fmt.Printf("\033[1mMarkers: %s (%s)\033[0m\n", addCommas(len(occurrences)), strings.Join(summary, ", "))
+	for _, o := range occurrences {
+		fmt.Printf("    %s:%d: %s\n", o.path, o.line, o.text)
+	}
+}
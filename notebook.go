@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// notebookLanguage is the language of Jupyter notebooks, whose cells are counted instead of their JSON.
+const notebookLanguage = "Jupyter"
+
+// notebook contains the parts of a Jupyter notebook which are counted. Cell outputs are ignored.
+type notebook struct {
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType string         `json:"cell_type"`
+		Source   notebookSource `json:"source"`
+	} `json:"cells"`
+}
+
+// notebookSource is the source of a notebook cell, which is stored as either a string or a list of lines.
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = notebookSource(strings.Join(lines, ""))
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(s))
+}
+
+/*
+countNotebook counts the cells of the Jupyter notebook read from reader, with code cells counted in
+the kernel's language and markdown cells counted as Markdown. Code cells are counted in f if the
+kernel's language isn't recognized.
+*/
+func (f *file) countNotebook(reader io.Reader) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		warn("Error reading file:", err)
+		return
+	}
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		warn("Error parsing notebook:", fmt.Errorf("%s: %w", f.fullPath, err))
+		return
+	}
+
+	kernel := nb.Metadata.KernelSpec.Language
+	if kernel == "" {
+		kernel = nb.Metadata.LanguageInfo.Name
+	}
+	kernelLang, ok := languageFromName(kernel)
+
+	// lineNum is the number of cell lines read so far, since notebooks' cells are numbered as if they were one file
+	var lineNum int
+	for _, cell := range nb.Cells {
+		var target *file
+		switch cell.CellType {
+		case "code":
+			target = f
+			if ok {
+				target = f.embeddedFile(kernelLang)
+			}
+		case "markdown":
+			target = f.embeddedFile("Markdown")
+		default:
+			// raw cells aren't in any language
+			continue
+		}
+
+		source := strings.TrimSuffix(string(cell.Source), "\n")
+		if source == "" {
+			continue
+		}
+		// each cell is classified separately, so unclosed strings and comments don't carry over
+		classifier := newLineClassifier(target.language)
+		for line := range strings.SplitSeq(source, "\n") {
+			lineNum++
+			target.countLine(strings.TrimSuffix(line, "\r"), lineNum, classifier)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import "strings"
+
+// statementRule is how a language's statements are separated, which is used to count logical lines of code.
+type statementRule int
+
+const (
+	// physicalStatements counts each line of code as a statement, for languages without a rule.
+	physicalStatements statementRule = iota
+	// semicolonStatements ends statements with semicolons, and counts each block opened with a brace.
+	semicolonStatements
+	// newlineStatements ends statements at the end of each line, unless they continue onto the next line.
+	newlineStatements
+)
+
+// statementRules maps languages to the rules used to count their statements.
+var statementRules = map[string]statementRule{
+	"C":                  semicolonStatements,
+	"C Header":           semicolonStatements,
+	"C#":                 semicolonStatements,
+	"C++":                semicolonStatements,
+	"C++ Header":         semicolonStatements,
+	"CSS":                semicolonStatements,
+	"D":                  semicolonStatements,
+	"Dart":               semicolonStatements,
+	"GLSL":               semicolonStatements,
+	"Java":               semicolonStatements,
+	"LESS":               semicolonStatements,
+	"Objective C":        semicolonStatements,
+	"Objective C++":      semicolonStatements,
+	"Objective-C":        semicolonStatements,
+	"PHP":                semicolonStatements,
+	"Perl":               semicolonStatements,
+	"Rust":               semicolonStatements,
+	"SQL":                semicolonStatements,
+	"Sass":               semicolonStatements,
+	"Solidity":           semicolonStatements,
+	"Zig":                semicolonStatements,
+	"BASH":               newlineStatements,
+	"CoffeeScript":       newlineStatements,
+	"Crystal":            newlineStatements,
+	"Dockerfile":         newlineStatements,
+	"Elixir":             newlineStatements,
+	"Fish":               newlineStatements,
+	"Go":                 newlineStatements,
+	"Groovy":             newlineStatements,
+	"JavaScript":         newlineStatements,
+	"JSX":                newlineStatements,
+	"Julia":              newlineStatements,
+	"Kotlin":             newlineStatements,
+	"Lua":                newlineStatements,
+	"Makefile":           newlineStatements,
+	"Nim":                newlineStatements,
+	"Powershell":         newlineStatements,
+	"Python":             newlineStatements,
+	"R":                  newlineStatements,
+	"Ruby":               newlineStatements,
+	"Scala":              newlineStatements,
+	"Shell":              newlineStatements,
+	"Swift":              newlineStatements,
+	"TSX":                newlineStatements,
+	"TypeScript":         newlineStatements,
+	"TypeScript Typings": newlineStatements,
+	"V":                  newlineStatements,
+	"Zsh":                newlineStatements,
+}
+
+var (
+	// continuationSuffixes contains the code which continues a statement onto the next line when it ends a line.
+	continuationSuffixes = []string{"\\", ",", ".", "(", "[", "=", "&&", "||"}
+	// continuationPrefixes contains the code which continues the previous line's statement when it begins a line.
+	continuationPrefixes = []string{".", "?.", ")", "]", "}", "&&", "||", "|>", "?"}
+	// headerPrefixes contains the keywords of statements whose headers can contain semicolons (e.g. Go's "for i := 0; i < n; i++ {").
+	headerPrefixes = []string{"for ", "if ", "switch ", "while ", "} else if "}
+)
+
+/*
+countStatements counts the statements which begin in a line's code, with comments removed and
+string literals replaced by a single quotation mark. Statements can span multiple lines, so each
+line's code must be counted in order.
+*/
+func (c *lineClassifier) countStatements(code string) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return
+	}
+
+	switch c.rule {
+	case physicalStatements:
+		c.statements++
+
+	case semicolonStatements:
+		for i := range len(code) {
+			switch code[i] {
+			case '(', '[':
+				c.depth++
+			case ')', ']':
+				c.depth = max(c.depth-1, 0)
+			case ';':
+				// semicolons inside parentheses separate the clauses of for loops
+				if c.depth == 0 {
+					c.statements++
+				}
+			case '{':
+				// braces after these characters open literals (e.g. "int a[] = {1, 2};") rather than blocks
+				if !strings.ContainsRune("=(,[", rune(c.lastCode)) {
+					c.statements++
+				}
+			}
+			if code[i] != ' ' && code[i] != '\t' {
+				c.lastCode = code[i]
+			}
+		}
+
+	case newlineStatements:
+		if c.depth == 0 && !c.continued && !hasAnyPrefix(code, continuationPrefixes) {
+			c.statements++
+		}
+		// semicolons in headers separate their clauses rather than statements, until the header ends with
+		// its closing parenthesis (e.g. "for (;;)") or, without parentheses, its opening brace (e.g. "for ;; {")
+		var header, parenthesized bool
+		for _, prefix := range headerPrefixes {
+			if strings.HasPrefix(code, prefix) {
+				header = true
+				parenthesized = strings.HasPrefix(strings.TrimSpace(code[len(prefix):]), "(")
+				break
+			}
+		}
+		for i := range len(code) {
+			switch code[i] {
+			case '(', '[':
+				c.depth++
+			case ')', ']':
+				c.depth = max(c.depth-1, 0)
+				if parenthesized && c.depth == 0 {
+					header = false
+				}
+			case '{':
+				if !parenthesized && c.depth == 0 {
+					header = false
+				}
+			case ';':
+				// semicolons can separate statements on the same line
+				if c.depth == 0 && !header && strings.TrimSpace(code[i+1:]) != "" {
+					c.statements++
+				}
+			}
+		}
+		c.continued = c.depth > 0 || hasAnySuffix(code, continuationSuffixes)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// testFilePatterns maps languages to the patterns of their test files' names, which are matched with path.Match.
+var testFilePatterns = map[string][]string{
+	"C#":         {"*Test.cs", "*Tests.cs"},
+	"Elixir":     {"*_test.exs"},
+	"Go":         {"*_test.go"},
+	"Java":       {"*Test.java", "*Tests.java", "*IT.java"},
+	"JavaScript": {"*.test.js", "*.spec.js", "*.test.jsx", "*.spec.jsx", "*.test.mjs", "*.spec.mjs"},
+	"Kotlin":     {"*Test.kt", "*Tests.kt"},
+	"PHP":        {"*Test.php"},
+	"Python":     {"test_*.py", "*_test.py", "conftest.py"},
+	"Ruby":       {"*_spec.rb", "*_test.rb"},
+	"Rust":       {"*_test.rs"},
+	"Scala":      {"*Spec.scala", "*Test.scala"},
+	"Swift":      {"*Tests.swift"},
+	"TypeScript": {"*.test.ts", "*.spec.ts", "*.test.tsx", "*.spec.tsx"},
+}
+
+// testDirNames contains the names of directories whose files are all tests (e.g. src/test/java).
+var testDirNames = []string{"test", "tests", "__tests__", "spec", "specs"}
+
+var (
+	// searchRoots contains the directories being searched, which test directories are found relative to.
+	searchRoots []string
+	// userTestPatterns contains the parsed file name patterns from the -tp flag.
+	userTestPatterns []string
+	// userTestDirs contains the parsed directory name patterns (those ending in "/") from the -tp flag.
+	userTestDirs []string
+)
+
+/*
+isTestFile reports whether a file is a test according to the conventions of its language, such as
+"_test.go" files, or because it is in a test directory, such as "__tests__". Patterns from -tp are
+used for all languages.
+*/
+func isTestFile(fullPath, lang string) bool {
+	fileName := filepath.Base(fullPath)
+	for _, pattern := range slices.Concat(testFilePatterns[lang], userTestPatterns) {
+		if matched, _ := path.Match(pattern, fileName); matched {
+			return true
+		}
+	}
+
+	// only check directories within the searched directory, so that searching inside a test directory works
+	dirPath := filepath.Dir(fullPath)
+	for _, root := range searchRoots {
+		if relPath, err := filepath.Rel(root, dirPath); err == nil && !strings.HasPrefix(relPath, "..") {
+			dirPath = relPath
+			break
+		}
+	}
+	for _, dirName := range splitPath(dirPath) {
+		for _, pattern := range slices.Concat(testDirNames, userTestDirs) {
+			if matched, _ := path.Match(pattern, dirName); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testRatio returns the ratio of test loc to production loc as a number of hundredths (e.g. 50 for 0.5).
+func testRatio(testLoc, loc int) int {
+	return safeDivide(testLoc*100, loc-testLoc)
+}
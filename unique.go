@@ -0,0 +1,60 @@
+package main
+
+import "hash/maphash"
+
+// uniqueLineSeed is the seed used to hash lines for unique line counts, which must be shared by all files.
+var uniqueLineSeed = maphash.MakeSeed()
+
+// addUniqueLine adds a trimmed, non-blank line to f's unique lines, if -u is used.
+func (f *file) addUniqueLine(line string) {
+	if !*uniqueFlag {
+		return
+	}
+	if f.uniqueLines == nil {
+		f.uniqueLines = make(map[uint64]bool)
+	}
+	// lines are stored as hashes to save memory, since every unique line in the tree may be stored
+	f.uniqueLines[maphash.String(uniqueLineSeed, line)] = true
+}
+
+// addLineSet adds the lines in src to the set of lines for a language in dst.
+func addLineSet(dst map[string]map[uint64]bool, language string, src map[uint64]bool) {
+	if len(src) == 0 {
+		return
+	}
+	if dst[language] == nil {
+		dst[language] = make(map[uint64]bool, len(src))
+	}
+	for line := range src {
+		dst[language][line] = true
+	}
+}
+
+/*
+mergeLineSet adds the lines in src to the set of lines for a language in dst, like addLineSet, but may
+reuse src rather than copying it, so src must not be used afterwards. This avoids copying the sets of
+files and subdirectories, which are released once they are added to their directories.
+*/
+func mergeLineSet(dst map[string]map[uint64]bool, language string, src map[uint64]bool) {
+	// add the smaller set to the larger one
+	if len(dst[language]) < len(src) {
+		dst[language], src = src, dst[language]
+	}
+	addLineSet(dst, language, src)
+}
+
+// countUniqueLines calculates d's numbers of unique lines by language and across all languages.
+func (d *directory) countUniqueLines() {
+	d.uniqueCounts = make(map[string]int, len(d.uniqueLines))
+	if len(d.uniqueLines) == 0 {
+		return
+	}
+	all := make(map[uint64]bool)
+	for language, lines := range d.uniqueLines {
+		d.uniqueCounts[language] = len(lines)
+		for line := range lines {
+			all[line] = true
+		}
+	}
+	d.uniqueTotal = len(all)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -47,6 +48,53 @@ func formatByteCount(byteCount int) string {
 	return fmt.Sprintf("%.1f gb", float64(byteCount)/1_000_000_000)
 }
 
+// formatRatio converts a ratio in hundredths into a string (e.g. "0.50" for 50).
+func formatRatio(hundredths int) string {
+	return fmt.Sprintf("%d.%02d", hundredths/100, hundredths%100)
+}
+
+// languageFiltered reports whether files in a language are excluded by -il or -el.
+func languageFiltered(lang string) bool {
+	if len(includeLangs) > 0 {
+		return !slices.Contains(includeLangs, lang)
+	}
+	return slices.Contains(excludeLangs, lang)
+}
+
+// hasAnyPrefix reports whether str begins with any of the given prefixes.
+func hasAnyPrefix(str string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(str, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnySuffix reports whether str ends with any of the given suffixes.
+func hasAnySuffix(str string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(str, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordChar reports whether a byte is a letter, digit, or underscore.
+func isWordChar(char byte) bool {
+	return char == '_' || '0' <= char && char <= '9' || 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z'
+}
+
+// maxMapValue returns the largest integer value of a map, or 0 if it is empty.
+func maxMapValue[k comparable](m map[k]int) int {
+	var result int
+	for _, value := range m {
+		result = max(result, value)
+	}
+	return result
+}
+
 // parentDir returns the path to the parent of the given entry.
 func parentDir(dirPath string) string {
 	pathParts := splitPath(dirPath)
@@ -132,13 +180,21 @@ func removeOverlappingDirs(dirPaths []string) []string {
 	return result
 }
 
-// sortFiles sorts a slice of files by loc or size.
-func sortFiles(slice []*file, sortBy string) []*file {
+// safeDivide performs integer division, returning 0 if the divisor is 0.
+func safeDivide(dividend, divisor int) int {
+	if divisor == 0 {
+		return 0
+	}
+	return dividend / divisor
+}
+
+// sortFiles sorts a slice of files by a column, or by loc if the column isn't printed by file.
+func sortFiles(slice []*file, sortBy *column) []*file {
+	if sortBy.fileCount == nil {
+		sortBy = findColumn("loc")
+	}
 	sort.Slice(slice, func(i, j int) bool {
-		if sortBy == "size" {
-			return slice[i].bytes > slice[j].bytes
-		}
-		return slice[i].loc > slice[j].loc
+		return sortBy.fileCount(slice[i]) > sortBy.fileCount(slice[j])
 	})
 	return slice
 }
@@ -160,6 +216,14 @@ func splitPath(path string) []string {
 	return strings.Split(path, "/")
 }
 
+// relToCwd returns a path relative to cwd for printing, or the path itself if it can't be made relative.
+func relToCwd(path string) string {
+	if relPath, err := filepath.Rel(cwd, path); err == nil {
+		return relPath
+	}
+	return path
+}
+
 // sumMapValues sums the integer values of a map.
 func sumMapValues[k comparable](m map[k]int) int {
 	var sum int
@@ -180,6 +244,19 @@ func toAbsPath(path string) string {
 	return path
 }
 
+// warnBinaryFiles prints the paths of the files which were skipped for being binary if -q is not used.
+func warnBinaryFiles() {
+	if *suppressWarningsFlag || len(binaryFiles) == 0 {
+		return
+	}
+
+	sort.Strings(binaryFiles)
+	fmt.Printf("Warning: skipped %s binary files with code extensions:\n", addCommas(len(binaryFiles)))
+	for _, path := range binaryFiles {
+		fmt.Printf("    %s\n", relToCwd(path))
+	}
+}
+
 // warn prints the message for a non-critical error if -q is not used.
 func warn(message string, err error) {
 	if !*suppressWarningsFlag {